@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"github.com/ahmadateya/my-own-k8s/manager"
+	sched "github.com/ahmadateya/my-own-k8s/scheduler"
 	"log"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -11,8 +13,12 @@ func init() {
 	rootCmd.AddCommand(managerCmd)
 	managerCmd.Flags().StringP("host", "H", "0.0.0.0", "Hostname or IP address")
 	managerCmd.Flags().IntP("port", "p", 5555, "Port on which to listen")
-	managerCmd.Flags().StringSliceP("workers", "w", []string{"localhost:5556"}, "List of workers on which the manager will schedule tasks.")
-	managerCmd.Flags().StringP("scheduler", "s", "epvm", "Name of scheduler to use.")
+	managerCmd.Flags().StringSliceP("workers", "w", []string{"localhost:5556"}, "List of workers on which the manager will schedule tasks. Workers started with --manager register themselves.")
+	managerCmd.Flags().StringP("scheduler", "s", "epvm", "Name of scheduler to use (\"roundrobin\", \"epvm\", \"binpack\" or \"spread\").")
+	managerCmd.Flags().String("extender", "", "URL of an HTTP scheduler extender to consult for filtering and scoring nodes.")
+	managerCmd.Flags().Duration("extender-timeout", 5*time.Second, "Timeout for each call to the scheduler extender.")
+	managerCmd.Flags().Bool("extender-fail-open", true, "Fall back to the built-in scheduler when the extender fails instead of refusing to place tasks.")
+	managerCmd.Flags().Float64("extender-weight", 1.0, "Multiplier applied to the extender's scores.")
 	managerCmd.Flags().StringP("dbType", "d", "memory", "Type of datastore to use for events and tasks (\"memory\" or \"persistent\")")
 }
 
@@ -32,16 +38,27 @@ The manager controls the orchestration system and is responsible for:
 		workers, _ := cmd.Flags().GetStringSlice("workers")
 		scheduler, _ := cmd.Flags().GetString("scheduler")
 		dbType, _ := cmd.Flags().GetString("dbType")
+		extender, _ := cmd.Flags().GetString("extender")
+		extenderTimeout, _ := cmd.Flags().GetDuration("extender-timeout")
+		extenderFailOpen, _ := cmd.Flags().GetBool("extender-fail-open")
+		extenderWeight, _ := cmd.Flags().GetFloat64("extender-weight")
 
 		// from []string to []manager.WorkerAddress
 
 		log.Println("Starting manager.")
 		m := manager.New(fromStringToWorkerAddress(workers), scheduler, dbType)
+		if extender != "" {
+			log.Printf("Using scheduler extender at %s", extender)
+			m.Scheduler = sched.NewExtender(m.Scheduler, extender, extenderTimeout, extenderFailOpen, extenderWeight)
+		}
 		api := manager.Api{Address: host, Port: port, Manager: m}
 		go m.ProcessTasks()
 		go m.UpdateTasks()
-		go m.DoHealthChecks()
 		go m.UpdateNodeStats()
+		go m.MonitorNodes()
+		go m.CollectTaskStats()
+		go m.ReconcileServices()
+		go m.RunAutoscalers()
 		log.Printf("Starting manager API on http://%s:%d", host, port)
 		api.Start()
 	},
@@ -4,39 +4,219 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	rootCmd.AddCommand(nodeCmd)
-	nodeCmd.Flags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	nodeCmd.PersistentFlags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	nodeCmd.AddCommand(cordonCmd)
+	nodeCmd.AddCommand(uncordonCmd)
+	nodeCmd.AddCommand(drainCmd)
 }
 
 var nodeCmd = &cobra.Command{
-	Use:   "node",
+	Use:   "node [name]",
 	Short: "Node command to list nodes.",
 	Long: `cube node command.
 
-The node command allows a user to get the information about the nodes in the cluster.`,
+The node command allows a user to get the information about the nodes in the cluster.
+Given the name of a node, it shows the details of that node.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		manager, _ := cmd.Flags().GetString("manager")
 
-		url := fmt.Sprintf("http://%s/nodes", manager)
-		resp, _ := http.Get(url)
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
+		if len(args) == 1 {
+			var n node.Node
+			getFromManager(fmt.Sprintf("http://%s/nodes/%s", manager, args[0]), &n)
+			printNode(&n)
+			return
+		}
+
 		var nodes []*node.Node
-		json.Unmarshal(body, &nodes)
+		getFromManager(fmt.Sprintf("http://%s/nodes", manager), &nodes)
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
-		fmt.Fprintln(w, "NAME\tMEMORY (MiB)\tDISK (GiB)\tROLE\tTASKS\t")
+		fmt.Fprintln(w, "NAME\tSTATUS\tMEMORY (MiB)\tDISK (GiB)\tROLE\tTASKS\t")
 		for _, node := range nodes {
-			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t\n", node.Name, node.Memory/1000, node.Disk/1000/1000/1000, node.Role, node.TaskCount)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%d\t\n", node.Name, nodeStatus(node), node.Memory/1000, node.Disk/1000/1000/1000, node.Role, node.TaskCount)
 		}
 		w.Flush()
 	},
 }
+
+func getFromManager(url string, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("Error sending request (%d): %s", resp.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func nodeStatus(n *node.Node) string {
+	status := string(n.Condition)
+	if n.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return status
+}
+
+func printNode(n *node.Node) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", n.Name)
+	fmt.Fprintf(w, "API:\t%s\n", n.Api)
+	fmt.Fprintf(w, "Role:\t%s\n", n.Role)
+	fmt.Fprintf(w, "Status:\t%s (since %s ago)\n", nodeStatus(n), units.HumanDuration(time.Since(n.LastTransition)))
+	fmt.Fprintf(w, "Last contact:\t%s ago\n", units.HumanDuration(time.Since(n.LastContact)))
+	fmt.Fprintf(w, "Labels:\t%s\n", formatLabels(n.Labels))
+	fmt.Fprintf(w, "Taints:\t%s\n", formatTaints(n.Taints))
+	if len(n.Pressure) > 0 {
+		fmt.Fprintf(w, "Pressure:\t%v\n", n.Pressure)
+	}
+	fmt.Fprintf(w, "Tasks:\t%d\n", n.TaskCount)
+	fmt.Fprintln(w, "Capacity:\t")
+	fmt.Fprintf(w, "  Cores:\t%d\n", n.Cores)
+	// node memory is reported in KiB while tasks request it in bytes
+	fmt.Fprintf(w, "  Memory:\t%s (%s allocated)\n", units.BytesSize(float64(n.Memory*1024)), units.BytesSize(float64(n.MemoryAllocated)))
+	fmt.Fprintf(w, "  Disk:\t%s (%s allocated)\n", units.BytesSize(float64(n.Disk)), units.BytesSize(float64(n.DiskAllocated)))
+
+	s := n.Stats
+	if s.MemStats != nil && s.DiskStats != nil && s.CpuStats != nil && s.LoadStats != nil {
+		fmt.Fprintln(w, "Stats:\t")
+		fmt.Fprintf(w, "  Memory used:\t%d MiB (%d MiB available)\n", s.MemUsedKb()/1000, s.MemAvailableKb()/1000)
+		fmt.Fprintf(w, "  Disk used:\t%d GiB (%d GiB free)\n", s.DiskUsed()/1000/1000/1000, s.DiskFree()/1000/1000/1000)
+		fmt.Fprintf(w, "  CPU usage:\t%.1f%%\n", s.CpuUsage()*100)
+		fmt.Fprintf(w, "  Load average:\t%.2f %.2f %.2f\n", s.LoadStats.Last1Min, s.LoadStats.Last5Min, s.LoadStats.Last15Min)
+		rx, tx := s.NetBytes()
+		fmt.Fprintf(w, "  Network:\t%d MiB received, %d MiB sent\n", rx/1000/1000, tx/1000/1000)
+		if p := s.PressureStats; p != nil && p.Cpu != nil && p.Memory != nil && p.Io != nil {
+			fmt.Fprintf(w, "  Pressure (avg10):\tcpu %.2f%%, memory %.2f%%, io %.2f%%\n", p.Cpu.Some.Avg10, p.Memory.Some.Avg10, p.Io.Some.Avg10)
+		}
+		var paths []string
+		for path := range s.MountStats {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			d := s.MountStats[path]
+			fmt.Fprintf(w, "  Disk used (%s):\t%d GiB (%d GiB free)\n", path, d.Used/1000/1000/1000, d.Free/1000/1000/1000)
+		}
+	}
+	w.Flush()
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "<none>"
+	}
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func formatTaints(taints []node.Taint) string {
+	if len(taints) == 0 {
+		return "<none>"
+	}
+	var ts []string
+	for _, t := range taints {
+		ts = append(ts, fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect))
+	}
+	return strings.Join(ts, ",")
+}
+
+var cordonCmd = &cobra.Command{
+	Use:   "cordon <name>",
+	Short: "Stop placing new tasks on a node.",
+	Long: `cube node cordon command.
+
+The cordon command marks a node unschedulable. Tasks already running on it keep running.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		postNodeAction(manager, args[0], "cordon")
+		log.Printf("Node %s cordoned.", args[0])
+	},
+}
+
+var uncordonCmd = &cobra.Command{
+	Use:   "uncordon <name>",
+	Short: "Return a node to service.",
+	Long: `cube node uncordon command.
+
+The uncordon command marks a cordoned or drained node schedulable again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		postNodeAction(manager, args[0], "uncordon")
+		log.Printf("Node %s uncordoned.", args[0])
+	},
+}
+
+var drainCmd = &cobra.Command{
+	Use:   "drain <name>",
+	Short: "Move all tasks off a node.",
+	Long: `cube node drain command.
+
+The drain command cordons a node, then stops each of its tasks within the task's grace
+period. Each task is rescheduled onto another node once the worker reports it stopped.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		body := postNodeAction(manager, args[0], "drain")
+
+		var tasks []*task.Task
+		err := json.Unmarshal(body, &tasks)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, t := range tasks {
+			log.Printf("Task %s (%s) is being stopped.", t.ID, t.Name)
+		}
+		log.Printf("Node %s is draining, %d tasks will be rescheduled once stopped.", args[0], len(tasks))
+	},
+}
+
+func postNodeAction(manager string, name string, action string) []byte {
+	url := fmt.Sprintf("http://%s/nodes/%s/%s", manager, name, action)
+	resp, err := http.Post(url, "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		log.Fatalf("Error sending request (%d): %s", resp.StatusCode, body)
+	}
+	return body
+}
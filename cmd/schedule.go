@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	explainCmd.Flags().StringP("filename", "f", "task.json", "Task specification file")
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Inspect scheduling decisions.",
+	Long: `cube schedule command.
+
+The schedule command groups subcommands that inspect how the manager schedules tasks.`,
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain where a task would be scheduled.",
+	Long: `cube schedule explain command.
+
+The explain command runs the manager's scheduler against a task specification without
+starting the task, and shows which nodes passed the filters, their scores and the node
+that would be picked.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		filename, _ := cmd.Flags().GetString("filename")
+
+		fullFilePath, err := filepath.Abs(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !fileExists(fullFilePath) {
+			log.Fatalf("File %s does not exist.", filename)
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			log.Fatalf("Unable to read file: %v", filename)
+		}
+
+		url := fmt.Sprintf("http://%s/schedule/dry-run", manager)
+		resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			log.Fatalf("Error sending request (%d): %s", resp.StatusCode, body)
+		}
+
+		var ex scheduler.Explanation
+		err = json.Unmarshal(body, &ex)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "NODE\tFEASIBLE\tSCORE\tREASON\t")
+		for _, v := range ex.Nodes {
+			score := "-"
+			if v.Feasible {
+				score = fmt.Sprintf("%.4f", v.Score)
+			}
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\t\n", v.Node, v.Feasible, score, v.Reason)
+		}
+		w.Flush()
+
+		if ex.Selected == "" {
+			fmt.Printf("\nTask %s cannot be scheduled on any node.\n", ex.TaskID)
+			if ex.Error != "" {
+				fmt.Printf("Scoring failed: %s\n", ex.Error)
+			}
+			return
+		}
+		fmt.Printf("\nTask %s would be scheduled on %s.\n", ex.TaskID, ex.Selected)
+	},
+}
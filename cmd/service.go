@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ahmadateya/my-own-k8s/service"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.PersistentFlags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	serviceCmd.AddCommand(serviceApplyCmd)
+	serviceApplyCmd.Flags().StringP("filename", "f", "service.json", "Service specification file")
+	serviceCmd.AddCommand(serviceScaleCmd)
+	serviceCmd.AddCommand(serviceDeleteCmd)
+
+	rootCmd.AddCommand(autoscalerCmd)
+	autoscalerCmd.PersistentFlags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	autoscalerCmd.AddCommand(autoscalerApplyCmd)
+	autoscalerApplyCmd.Flags().StringP("filename", "f", "autoscaler.json", "Autoscaler specification file")
+	autoscalerCmd.AddCommand(autoscalerDeleteCmd)
+}
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Service command to list replicated services.",
+	Long: `cube service command.
+
+The service command lists the replicated services known to the manager, along with how many
+of their replicas are running.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+
+		var services []service.Status
+		getFromManager(fmt.Sprintf("http://%s/services", manager), &services)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "NAME\tREPLICAS\tRUNNING\tACTIVE\tIMAGE\t")
+		for _, s := range services {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t\n", s.Name, s.Replicas, s.Running, s.Active, s.Template.Image)
+		}
+		w.Flush()
+	},
+}
+
+var serviceApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update a service.",
+	Long: `cube service apply command.
+
+The apply command creates the service described in a file, or updates the replica count and
+task template of an existing service with the same name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		filename, _ := cmd.Flags().GetString("filename")
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			log.Fatalf("Unable to read file: %v", filename)
+		}
+
+		sendToManager(http.MethodPost, fmt.Sprintf("http://%s/services", manager), data)
+		log.Println("Successfully applied service")
+	},
+}
+
+var serviceScaleCmd = &cobra.Command{
+	Use:   "scale <name> <replicas>",
+	Short: "Set the number of replicas of a service.",
+	Long: `cube service scale command.
+
+The scale command sets the number of replicas of a service. An autoscaler targeting the
+service may change it again.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		replicas, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("Invalid number of replicas %q", args[1])
+		}
+
+		data, _ := json.Marshal(map[string]int{"Replicas": replicas})
+		sendToManager(http.MethodPost, fmt.Sprintf("http://%s/services/%s/scale", manager, args[0]), data)
+		log.Printf("Service %s scaled to %d replicas.", args[0], replicas)
+	},
+}
+
+var serviceDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Stop all replicas of a service and remove it.",
+	Long: `cube service delete command.
+
+The delete command stops all replicas of a service and removes it along with its autoscalers.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		sendToManager(http.MethodDelete, fmt.Sprintf("http://%s/services/%s", manager, args[0]), nil)
+		log.Printf("Service %s deleted.", args[0])
+	},
+}
+
+var autoscalerCmd = &cobra.Command{
+	Use:   "autoscaler",
+	Short: "Autoscaler command to list horizontal autoscalers.",
+	Long: `cube autoscaler command.
+
+The autoscaler command lists the horizontal autoscalers known to the manager, along with the
+utilization they last measured.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+
+		var autoscalers []*service.HorizontalAutoscaler
+		getFromManager(fmt.Sprintf("http://%s/autoscalers", manager), &autoscalers)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "NAME\tSERVICE\tCPU\tMEMORY\tMIN\tMAX\tDESIRED\t")
+		for _, h := range autoscalers {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t\n", h.Name, h.Service,
+				formatUtilization(h.CurrentCpuUtilization, h.TargetCpuUtilization),
+				formatUtilization(h.CurrentMemoryUtilization, h.TargetMemoryUtilization),
+				h.MinReplicas, h.MaxReplicas, h.DesiredReplicas)
+		}
+		w.Flush()
+	},
+}
+
+func formatUtilization(current float64, target float64) string {
+	if target <= 0 {
+		return "-"
+	}
+	if current < 0 {
+		return fmt.Sprintf("<unknown>/%.0f%%", target)
+	}
+	return fmt.Sprintf("%.0f%%/%.0f%%", current, target)
+}
+
+var autoscalerApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or replace an autoscaler.",
+	Long: `cube autoscaler apply command.
+
+The apply command creates the horizontal autoscaler described in a file, replacing an existing
+autoscaler with the same name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		filename, _ := cmd.Flags().GetString("filename")
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			log.Fatalf("Unable to read file: %v", filename)
+		}
+
+		sendToManager(http.MethodPost, fmt.Sprintf("http://%s/autoscalers", manager), data)
+		log.Println("Successfully applied autoscaler")
+	},
+}
+
+var autoscalerDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove an autoscaler.",
+	Long: `cube autoscaler delete command.
+
+The delete command removes an autoscaler. The service keeps its current number of replicas.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+		sendToManager(http.MethodDelete, fmt.Sprintf("http://%s/autoscalers/%s", manager, args[0]), nil)
+		log.Printf("Autoscaler %s deleted.", args[0])
+	},
+}
+
+func sendToManager(method string, url string, data []byte) []byte {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
+	if err != nil {
+		log.Fatalf("Error creating request %v: %v", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode >= 300 {
+		log.Fatalf("Error sending request (%d): %s", resp.StatusCode, body)
+	}
+	return body
+}
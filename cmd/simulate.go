@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/ahmadateya/my-own-k8s/simulator"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().StringP("nodes", "n", "nodes.json", "JSON file with the synthetic nodes and their capacities")
+	simulateCmd.Flags().StringP("trace", "t", "trace.jsonl", "Workload trace with one task submission per line")
+	simulateCmd.Flags().StringSliceP("scheduler", "s", []string{"roundrobin", "epvm", "binpack", "spread"}, "Schedulers to compare.")
+}
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Replay a workload trace against schedulers offline.",
+	Long: `cube simulate command.
+
+The simulate command replays a trace of task submissions against a set of synthetic nodes
+with a virtual clock, once per scheduler, and reports utilization, fragmentation, pending
+time and failures for each of them. No manager or workers are needed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		nodesFile, _ := cmd.Flags().GetString("nodes")
+		traceFile, _ := cmd.Flags().GetString("trace")
+		schedulers, _ := cmd.Flags().GetStringSlice("scheduler")
+
+		trace, err := simulator.LoadTrace(traceFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "SCHEDULER\tPLACED\tFAILED\tREJECTED\tAVG PENDING (s)\tMAX PENDING (s)\tMEM UTIL\tDISK UTIL\tFRAGMENTATION\tMAKESPAN (s)\t")
+		for _, name := range schedulers {
+			// every run starts from freshly loaded nodes so allocations don't leak between schedulers
+			nodes, err := simulator.LoadNodes(nodesFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			r := simulator.New(name, scheduler.New(name), nodes, trace).Run()
+			fmt.Fprintf(w, "%s\t%d/%d\t%d\t%d\t%.1f\t%.1f\t%.1f%%\t%.1f%%\t%.3f\t%.1f\t\n",
+				r.Scheduler, r.Placed, r.Submitted, r.Failed, r.RejectedPlacement,
+				r.AvgPendingTime, r.MaxPendingTime, r.MemoryUtilization*100, r.DiskUtilization*100,
+				r.Fragmentation, r.Makespan)
+		}
+		w.Flush()
+	},
+}
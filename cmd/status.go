@@ -44,7 +44,7 @@ The status command allows a user to get the status of tasks from the Cube manage
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
-		fmt.Fprintln(w, "ID\tNAME\tCREATED\tSTATE\tCONTAINERNAME\tIMAGE\t")
+		fmt.Fprintln(w, "ID\tNAME\tCREATED\tSTATE\tRESTARTS\tCONTAINERNAME\tIMAGE\t")
 		for _, task := range tasks {
 			var start string
 			if task.StartTime.IsZero() {
@@ -53,9 +53,7 @@ The status command allows a user to get the status of tasks from the Cube manage
 				start = fmt.Sprintf("%s ago", units.HumanDuration(time.Now().UTC().Sub(task.StartTime)))
 			}
 
-			// TODO: there is a bug here, state for stopped jobs is showing as Running
-			//state := task.State.String()[task.State]
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", task.ID, task.Name, start, "state", task.Name, task.Image)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t\n", task.ID, task.Name, start, task.State.String(), task.RestartCount, task.Name, task.Image)
 		}
 		w.Flush()
 	},
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	topCmd.PersistentFlags().StringP("manager", "m", "localhost:5555", "Manager to talk to")
+	topCmd.AddCommand(topTasksCmd)
+}
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show resource usage.",
+	Long: `cube top command.
+
+The top command shows the resource usage collected by the manager.`,
+}
+
+var topTasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "Show the resource usage of running tasks.",
+	Long: `cube top tasks command.
+
+The tasks command shows the CPU, memory, network and block IO usage of each running task,
+as last collected by the manager from the workers.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, _ := cmd.Flags().GetString("manager")
+
+		var taskStats []*stats.TaskStats
+		getFromManager(fmt.Sprintf("http://%s/tasks/stats", manager), &taskStats)
+		sort.Slice(taskStats, func(i, j int) bool {
+			return taskStats[i].CpuPercent > taskStats[j].CpuPercent
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "ID\tNAME\tCPU %\tMEMORY\tMEM %\tNET RX / TX\tBLOCK R / W\t")
+		for _, s := range taskStats {
+			fmt.Fprintf(w, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s / %s\t%s / %s\t\n",
+				s.TaskID, s.TaskName, s.CpuPercent,
+				units.BytesSize(float64(s.MemoryUsage)), units.BytesSize(float64(s.MemoryLimit)), s.MemoryPercent(),
+				units.HumanSize(float64(s.NetworkRx)), units.HumanSize(float64(s.NetworkTx)),
+				units.HumanSize(float64(s.BlockRead)), units.HumanSize(float64(s.BlockWrite)))
+		}
+		w.Flush()
+	},
+}
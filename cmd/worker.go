@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/ahmadateya/my-own-k8s/task"
 	"github.com/ahmadateya/my-own-k8s/worker"
 	"log"
+	"os"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
@@ -14,6 +18,19 @@ func init() {
 	workerCmd.Flags().StringP("host", "H", "0.0.0.0", "Hostname or IP address")
 	workerCmd.Flags().IntP("port", "p", 5556, "Port on which to listen")
 	workerCmd.Flags().StringP("name", "n", fmt.Sprintf("worker-%s", uuid.New().String()), "Name of the worker")
+	workerCmd.Flags().StringP("manager", "m", "", "Manager to register with; when empty the worker waits to be listed in the manager's --workers")
+	workerCmd.Flags().String("advertise", "", "Address the manager should use to reach this worker (default <hostname>:<port>)")
+	workerCmd.Flags().StringToStringP("labels", "l", map[string]string{}, "Labels to register the worker with, e.g. zone=us-east-1a")
+	workerCmd.Flags().Duration("heartbeat-interval", 10*time.Second, "How often to send heartbeats to the manager")
+	workerCmd.Flags().StringSlice("mounts", []string{"/var/lib/docker"}, "Paths whose filesystem usage is reported besides the root filesystem, e.g. the Docker data root")
+	workerCmd.Flags().String("eviction-memory-available", "100MiB", "Evict tasks when available memory drops below this size, 0 disables")
+	workerCmd.Flags().String("eviction-disk-free", "1GiB", "Evict tasks when free space on the root filesystem or a monitored mount drops below this size, 0 disables")
+	workerCmd.Flags().Float64("eviction-memory-pressure", 0, "Evict tasks when tasks were stalled on memory for more than this percentage of the last 10 seconds, 0 disables")
+	workerCmd.Flags().Float64("eviction-io-pressure", 0, "Evict tasks when tasks were stalled on IO for more than this percentage of the last 10 seconds, 0 disables")
+	workerCmd.Flags().Duration("restart-backoff", task.DefaultRestartBackoff, "Delay before a task's container is first restarted, doubled with every restart")
+	workerCmd.Flags().Duration("restart-backoff-max", task.DefaultMaxRestartBackoff, "Maximum delay between restarts of a task's container")
+	workerCmd.Flags().Int("parallelism", worker.DefaultParallelism, "Number of task operations, such as starting a task's container, to run at the same time")
+	workerCmd.Flags().Bool("remove-unknown-containers", true, "Remove the containers labeled with the worker's name that don't belong to any of its tasks on startup")
 	workerCmd.Flags().StringP("dbtype", "d", "memory", "Type of datastore to use for tasks (\"memory\" or \"persistent\")")
 }
 
@@ -28,14 +45,66 @@ The worker runs tasks and responds to the manager's requests about task state.`,
 		port, _ := cmd.Flags().GetInt("port")
 		name, _ := cmd.Flags().GetString("name")
 		dbType, _ := cmd.Flags().GetString("dbtype")
+		manager, _ := cmd.Flags().GetString("manager")
+		advertise, _ := cmd.Flags().GetString("advertise")
+		labels, _ := cmd.Flags().GetStringToString("labels")
+		heartbeatInterval, _ := cmd.Flags().GetDuration("heartbeat-interval")
+		mounts, _ := cmd.Flags().GetStringSlice("mounts")
+		memoryAvailable, _ := cmd.Flags().GetString("eviction-memory-available")
+		diskFree, _ := cmd.Flags().GetString("eviction-disk-free")
+		memoryPressure, _ := cmd.Flags().GetFloat64("eviction-memory-pressure")
+		ioPressure, _ := cmd.Flags().GetFloat64("eviction-io-pressure")
+		restartBackoff, _ := cmd.Flags().GetDuration("restart-backoff")
+		restartBackoffMax, _ := cmd.Flags().GetDuration("restart-backoff-max")
+		parallelism, _ := cmd.Flags().GetInt("parallelism")
+		removeUnknown, _ := cmd.Flags().GetBool("remove-unknown-containers")
+
+		eviction := worker.EvictionThresholds{
+			MemoryAvailable: parseSize("eviction-memory-available", memoryAvailable),
+			DiskFree:        parseSize("eviction-disk-free", diskFree),
+			MemoryPressure:  memoryPressure,
+			IoPressure:      ioPressure,
+		}
 
 		log.Println("Starting worker.")
 		w := worker.New(name, dbType)
+		w.MountPaths = mounts
+		w.Eviction = eviction
+		w.RestartBackoff = restartBackoff
+		w.MaxRestartBackoff = restartBackoffMax
+		w.Parallelism = parallelism
+		w.RemoveUnknownContainers = removeUnknown
+		err := w.Reconcile()
+		if err != nil {
+			log.Printf("Unable to reconcile tasks with their containers: %v\n", err)
+		}
 		api := worker.Api{Address: host, Port: port, Worker: w}
 		go w.RunTasks()
 		go w.CollectStats()
 		go w.UpdateTasks()
+		go w.WatchContainers()
+		go w.RunProbes()
+		if manager != "" {
+			if advertise == "" {
+				hostname, err := os.Hostname()
+				if err != nil {
+					log.Fatalf("unable to determine hostname, use --advertise: %v", err)
+				}
+				advertise = fmt.Sprintf("%s:%d", hostname, port)
+			}
+			go w.RegisterWithManager(manager, advertise, labels, heartbeatInterval)
+			go w.PushTaskUpdates(manager, advertise)
+		}
 		log.Printf("Starting worker API on http://%s:%d", host, port)
 		api.Start()
 	},
 }
+
+// parseSize parses a size flag such as 100MiB into bytes.
+func parseSize(flag string, value string) uint64 {
+	bytes, err := units.RAMInBytes(value)
+	if err != nil || bytes < 0 {
+		log.Fatalf("invalid --%s %q: %v", flag, value, err)
+	}
+	return uint64(bytes)
+}
@@ -21,13 +21,50 @@ type Api struct {
 
 func (a *Api) initRouter() {
 	a.Router = chi.NewRouter()
+	a.Router.Use(httpMetrics.Middleware)
+	registry.OnCollect(a.Manager.updateMetrics)
+	a.Router.Get("/metrics", registry.Handler())
 	a.Router.Route("/tasks", func(r chi.Router) {
 		r.Post("/", a.StartTaskHandler)
 		r.Get("/", a.GetTasksHandler)
+		r.Get("/stats", a.GetTasksStatsHandler)
+		r.Post("/status", a.TaskStatusHandler)
 		r.Route("/{taskID}", func(r chi.Router) {
 			r.Delete("/", a.StopTaskHandler)
+			r.Get("/stats", a.GetTaskStatsHandler)
 		})
 	})
+	a.Router.Route("/nodes", func(r chi.Router) {
+		r.Get("/", a.GetNodesHandler)
+		r.Post("/register", a.RegisterNodeHandler)
+		r.Post("/heartbeat", a.HeartbeatHandler)
+		r.Route("/{nodeName}", func(r chi.Router) {
+			r.Get("/", a.GetNodeHandler)
+			r.Post("/taints", a.AddTaintHandler)
+			r.Delete("/taints/{key}", a.RemoveTaintHandler)
+			r.Post("/labels", a.SetLabelsHandler)
+			r.Post("/cordon", a.CordonNodeHandler)
+			r.Post("/uncordon", a.UncordonNodeHandler)
+			r.Post("/drain", a.DrainNodeHandler)
+		})
+	})
+	a.Router.Route("/services", func(r chi.Router) {
+		r.Post("/", a.ApplyServiceHandler)
+		r.Get("/", a.GetServicesHandler)
+		r.Route("/{serviceName}", func(r chi.Router) {
+			r.Get("/", a.GetServiceHandler)
+			r.Delete("/", a.DeleteServiceHandler)
+			r.Post("/scale", a.ScaleServiceHandler)
+		})
+	})
+	a.Router.Route("/autoscalers", func(r chi.Router) {
+		r.Post("/", a.ApplyAutoscalerHandler)
+		r.Get("/", a.GetAutoscalersHandler)
+		r.Delete("/{autoscalerName}", a.DeleteAutoscalerHandler)
+	})
+	a.Router.Route("/schedule", func(r chi.Router) {
+		r.Post("/dry-run", a.ScheduleDryRunHandler)
+	})
 }
 
 func (a *Api) Start() {
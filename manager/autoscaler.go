@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/service"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// ApplyAutoscaler creates the autoscaler or replaces an existing one with the same name.
+func (m *Manager) ApplyAutoscaler(h service.HorizontalAutoscaler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := h.Validate()
+	if err != nil {
+		return err
+	}
+	if _, ok := m.Services[h.Service]; !ok {
+		return fmt.Errorf("service %s not found", h.Service)
+	}
+	if h.ScaleDownStabilization == 0 {
+		h.ScaleDownStabilization = service.DefaultScaleDownStabilization
+	}
+	// nothing has been measured yet
+	h.CurrentCpuUtilization = -1
+	h.CurrentMemoryUtilization = -1
+	hpa := h
+	m.Autoscalers[h.Name] = &hpa
+	log.Printf("[manager] applied autoscaler %s for service %s\n", h.Name, h.Service)
+	return nil
+}
+
+// GetAutoscalers returns copies of the autoscalers, which the caller may read without holding the lock.
+func (m *Manager) GetAutoscalers() []*service.HorizontalAutoscaler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	autoscalers := []*service.HorizontalAutoscaler{}
+	for _, h := range m.Autoscalers {
+		hpa := *h
+		autoscalers = append(autoscalers, &hpa)
+	}
+	sort.Slice(autoscalers, func(i, j int) bool { return autoscalers[i].Name < autoscalers[j].Name })
+	return autoscalers
+}
+
+func (m *Manager) DeleteAutoscaler(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Autoscalers[name]; !ok {
+		return fmt.Errorf("autoscaler %s not found", name)
+	}
+	delete(m.Autoscalers, name)
+	log.Printf("[manager] deleted autoscaler %s\n", name)
+	return nil
+}
+
+// RunAutoscalers periodically adjusts the replicas of autoscaled services, using the task stats
+// collected from the workers by CollectTaskStats.
+func (m *Manager) RunAutoscalers() {
+	for {
+		m.mu.Lock()
+		for _, h := range m.Autoscalers {
+			m.autoscale(h)
+		}
+		m.mu.Unlock()
+		time.Sleep(15 * time.Second)
+	}
+}
+
+func (m *Manager) autoscale(h *service.HorizontalAutoscaler) {
+	s, ok := m.Services[h.Service]
+	if !ok {
+		log.Printf("[manager] autoscaler %s: service %s not found\n", h.Name, h.Service)
+		return
+	}
+
+	u := m.serviceUtilization(s.Name)
+	desired := h.Recommend(s.Replicas, u, time.Now())
+	if desired == s.Replicas {
+		return
+	}
+
+	log.Printf("[manager] autoscaler %s: cpu %.1f%%, memory %.1f%%, scaling service %s from %d to %d replicas\n",
+		h.Name, u.Cpu, u.Memory, s.Name, s.Replicas, desired)
+	h.LastScaleTime = time.Now().UTC()
+	m.scaleService(s.Name, desired)
+}
+
+// serviceUtilization averages the usage of the service's running replicas relative to their
+// requests. Replicas without stats or without a request for a resource are left out of that
+// resource's average.
+func (m *Manager) serviceUtilization(name string) service.Utilization {
+	var cpuTotal, memoryTotal float64
+	var cpuCount, memoryCount int
+	for _, t := range m.replicas(name) {
+		if t.State != task.Running {
+			continue
+		}
+		s, ok := m.TaskStats[t.ID]
+		if !ok {
+			continue
+		}
+		if t.Cpu > 0 {
+			// CpuPercent counts one fully used core as 100%
+			cpuTotal += s.CpuPercent / t.Cpu
+			cpuCount++
+		}
+		if t.Memory > 0 {
+			memoryTotal += float64(s.MemoryUsage) / float64(t.Memory) * 100
+			memoryCount++
+		}
+	}
+
+	u := service.Utilization{Cpu: -1, Memory: -1}
+	if cpuCount > 0 {
+		u.Cpu = cpuTotal / float64(cpuCount)
+	}
+	if memoryCount > 0 {
+		u.Memory = memoryTotal / float64(memoryCount)
+	}
+	return u
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+// DefaultGangTimeout is how long a gang may hold reservations before it is given up on.
+const DefaultGangTimeout = 5 * time.Minute
+
+// gang collects the task events of a job that must be placed all-or-nothing. Capacity found for
+// a member is reserved on its node straight away, and nothing is sent to the workers until
+// every member has a reservation.
+type gang struct {
+	job          string
+	size         int
+	events       []task.Event
+	reservations map[uuid.UUID]*node.Node // [taskID]node
+	created      time.Time
+}
+
+// validateGang checks that a task asking for a gang names the job the gang is formed from.
+func validateGang(t task.Task) error {
+	if t.GangSize > 1 && t.Job == "" {
+		return fmt.Errorf("task %s has a gang size of %d but no job", t.ID, t.GangSize)
+	}
+	return nil
+}
+
+func (m *Manager) addToGang(te task.Event) {
+	err := validateGang(te.Task)
+	if err != nil {
+		log.Printf("[manager] %v, failing it\n", err)
+		t := te.Task
+		t.State = task.Failed
+		t.FinishTime = time.Now().UTC()
+		m.TaskDb.Put(t.ID.String(), &t)
+		return
+	}
+
+	job := te.Task.Job
+	g, ok := m.gangs[job]
+	if !ok {
+		g = &gang{
+			job:          job,
+			size:         te.Task.GangSize,
+			reservations: make(map[uuid.UUID]*node.Node),
+			created:      time.Now(),
+		}
+		m.gangs[job] = g
+	}
+
+	for _, e := range g.events {
+		if e.Task.ID == te.Task.ID {
+			log.Printf("[manager] task %s is already part of gang %s\n", te.Task.ID, job)
+			return
+		}
+	}
+	g.events = append(g.events, te)
+	log.Printf("[manager] gang %s has %d of %d tasks\n", job, len(g.events), g.size)
+}
+
+// scheduleGangs tries to reserve capacity for the members of every gang still waiting, dispatches
+// the gangs that are fully reserved and gives up on the ones that exceeded the gang timeout.
+func (m *Manager) scheduleGangs() {
+	for job, g := range m.gangs {
+		if len(g.events) >= g.size {
+			m.reserveGang(g)
+		}
+
+		if len(g.reservations) >= g.size {
+			log.Printf("[manager] all %d tasks of gang %s reserved, dispatching\n", g.size, job)
+			m.dispatchGang(g)
+			delete(m.gangs, job)
+			continue
+		}
+
+		if time.Since(g.created) > m.GangTimeout {
+			log.Printf("[manager] gang %s could not be placed within %v, releasing its reservations\n", job, m.GangTimeout)
+			m.abandonGang(g)
+			delete(m.gangs, job)
+		}
+	}
+}
+
+func (m *Manager) reserveGang(g *gang) {
+	for _, te := range g.events {
+		if len(g.reservations) >= g.size {
+			return
+		}
+		if _, ok := g.reservations[te.Task.ID]; ok {
+			continue
+		}
+
+		w, err := m.selectWorker(te.Task)
+		if err != nil {
+			log.Printf("[manager] no capacity yet for task %s of gang %s: %v\n", te.Task.ID, g.job, err)
+			return
+		}
+		allocate(w, te.Task)
+		g.reservations[te.Task.ID] = w
+		log.Printf("[manager] reserved worker %s for task %s of gang %s\n", w.Name, te.Task.ID, g.job)
+	}
+}
+
+// dispatchGang sends the reserved members of a gang to their workers. If a worker doesn't accept
+// its member, the members already sent are stopped and the whole gang goes back to the pending
+// queue to be placed again.
+func (m *Manager) dispatchGang(g *gang) {
+	var sent []task.Event
+	var failed error
+	for _, te := range g.events {
+		w, ok := g.reservations[te.Task.ID]
+		if !ok {
+			continue
+		}
+		if failed != nil {
+			release(w, te.Task)
+			continue
+		}
+		failed = m.sendToWorker(te, w)
+		if failed == nil {
+			sent = append(sent, te)
+		}
+	}
+
+	if failed != nil {
+		log.Printf("[manager] %v, requeueing gang %s\n", failed, g.job)
+		for _, te := range sent {
+			t := te.Task
+			m.stopTask(m.TaskWorkerMap[t.ID], t.ID.String())
+			m.unassignTask(&t)
+			t.State = task.Pending
+			m.TaskDb.Put(t.ID.String(), &t)
+		}
+		for _, te := range g.events {
+			m.Pending.Backoff(te)
+		}
+		return
+	}
+
+	for _, te := range g.events {
+		if _, ok := g.reservations[te.Task.ID]; ok {
+			continue
+		}
+		// the gang's minimum is met, so tasks beyond it are placed on their own
+		te.Task.GangSize = 0
+		m.Pending.Enqueue(te)
+	}
+}
+
+func (m *Manager) abandonGang(g *gang) {
+	for id, w := range g.reservations {
+		for _, te := range g.events {
+			if te.Task.ID == id {
+				release(w, te.Task)
+			}
+		}
+	}
+
+	for _, te := range g.events {
+		t := te.Task
+		t.State = task.Failed
+		t.FinishTime = time.Now().UTC()
+		m.TaskDb.Put(t.ID.String(), &t)
+	}
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+func newGang(job string, size int, memory uint64) *gang {
+	g := &gang{job: job, size: size, reservations: make(map[uuid.UUID]*node.Node), created: time.Now()}
+	for i := 0; i < size; i++ {
+		t := task.Task{ID: uuid.New(), Job: job, GangSize: size, Memory: memory}
+		g.events = append(g.events, task.Event{ID: uuid.New(), State: task.Scheduled, Task: t})
+	}
+	return g
+}
+
+func TestDispatchGangRollsBackWhenAMemberIsRejected(t *testing.T) {
+	accepting := newFakeWorker(t)
+	rejecting := newFakeWorker(t)
+	rejecting.startStatus = http.StatusInternalServerError
+	m := New([]WorkerAddress{accepting.address(), rejecting.address()}, "roundrobin", "memory")
+	a, r := m.WorkerNodes[0], m.WorkerNodes[1]
+
+	g := newGang("train", 3, 100_000)
+	// the first member is sent, the second rejected and the third never sent
+	for i, n := range []*node.Node{a, r, a} {
+		allocate(n, g.events[i].Task)
+		g.reservations[g.events[i].Task.ID] = n
+	}
+
+	m.dispatchGang(g)
+
+	sent := g.events[0].Task.ID
+	if !accepting.wasStopped(sent) {
+		t.Error("the member already sent should be stopped")
+	}
+	for _, n := range m.WorkerNodes {
+		if n.MemoryAllocated != 0 || n.TaskCount != 0 {
+			t.Errorf("node %s has %d bytes allocated to %d tasks, want the reservations released", n.Name, n.MemoryAllocated, n.TaskCount)
+		}
+	}
+	for _, te := range g.events {
+		if _, ok := m.TaskWorkerMap[te.Task.ID]; ok {
+			t.Errorf("task %s should no longer be assigned to a worker", te.Task.ID)
+		}
+	}
+	for _, te := range g.events[:2] {
+		if s := taskState(m, te.Task.ID); s != task.Pending {
+			t.Errorf("task %s is %s, want Pending", te.Task.ID, s)
+		}
+	}
+	if m.Pending.Len() != 3 {
+		t.Errorf("pending queue has %d events, want the whole gang", m.Pending.Len())
+	}
+}
+
+func TestScheduleGangsReleasesReservationsAfterTimeout(t *testing.T) {
+	f := newFakeWorker(t)
+	m := New([]WorkerAddress{f.address()}, "binpack", "memory")
+	m.WorkerNodes[0].Memory = 250 // KiB, room for two of the three members
+	m.GangTimeout = 0
+
+	g := newGang("train", 3, 100_000)
+	m.gangs[g.job] = g
+	m.scheduleGangs()
+
+	if _, ok := m.gangs[g.job]; ok {
+		t.Error("the gang should be given up on")
+	}
+	if n := m.WorkerNodes[0]; n.MemoryAllocated != 0 || n.TaskCount != 0 {
+		t.Errorf("node has %d bytes allocated to %d tasks, want the reservations released", n.MemoryAllocated, n.TaskCount)
+	}
+	for _, te := range g.events {
+		if s := taskState(m, te.Task.ID); s != task.Failed {
+			t.Errorf("task %s is %s, want Failed", te.Task.ID, s)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package manager
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/service"
 	"github.com/ahmadateya/my-own-k8s/task"
 	"log"
 	"net/http"
@@ -29,6 +32,18 @@ func (a *Api) StartTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = validateGang(te.Task)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
 	a.Manager.AddTask(te)
 	log.Printf("Added task %v\n", te.Task.ID)
 	w.WriteHeader(201)
@@ -70,3 +85,460 @@ func (a *Api) StopTaskHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Added task event %v to stop task %v\n", te.ID, taskCopy.ID)
 	w.WriteHeader(204)
 }
+
+func (a *Api) AddTaintHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	d := json.NewDecoder(r.Body)
+
+	taint := node.Taint{}
+	err := d.Decode(&taint)
+	if err == nil {
+		err = taint.Validate()
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	err = a.Manager.AddTaint(nodeName, taint)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(201)
+	json.NewEncoder(w).Encode(taint)
+}
+
+func (a *Api) RemoveTaintHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	key := chi.URLParam(r, "key")
+
+	err := a.Manager.RemoveTaint(nodeName, key)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (a *Api) ScheduleDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+
+	te := task.Event{}
+	err := d.Decode(&te)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Manager.ExplainSchedule(te.Task))
+}
+
+func (a *Api) SetLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	d := json.NewDecoder(r.Body)
+
+	labels := map[string]string{}
+	err := d.Decode(&labels)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	err = a.Manager.SetLabels(nodeName, labels)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (a *Api) RegisterNodeHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+
+	reg := node.Registration{}
+	err := d.Decode(&reg)
+	if err == nil && reg.Address == "" {
+		err = fmt.Errorf("address must not be empty")
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	n := a.Manager.RegisterNode(reg)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	json.NewEncoder(w).Encode(n)
+}
+
+func (a *Api) HeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+
+	hb := node.Heartbeat{}
+	err := d.Decode(&hb)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	err = a.Manager.Heartbeat(hb)
+	if err != nil {
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// TaskStatusHandler receives the task updates pushed by a worker.
+func (a *Api) TaskStatusHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+
+	report := node.TaskStatusReport{}
+	err := d.Decode(&report)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	err = a.Manager.ReportTaskStatus(report)
+	if err != nil {
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (a *Api) CordonNodeHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	err := a.Manager.Cordon(nodeName)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (a *Api) UncordonNodeHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	err := a.Manager.Uncordon(nodeName)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (a *Api) DrainNodeHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	tasks, err := a.Manager.Drain(nodeName)
+	if err != nil {
+		log.Println(err)
+		status := 404
+		if errors.Is(err, ErrNotStopped) {
+			status = 502
+		}
+		w.WriteHeader(status)
+		e := ErrResponse{
+			HTTPStatusCode: status,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(tasks)
+}
+
+func (a *Api) GetNodesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Manager.GetNodes())
+}
+
+func (a *Api) GetNodeHandler(w http.ResponseWriter, r *http.Request) {
+	nodeName := chi.URLParam(r, "nodeName")
+	n, err := a.Manager.GetNode(nodeName)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(n)
+}
+
+func (a *Api) GetTasksStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Manager.GetTaskStats())
+}
+
+func (a *Api) GetTaskStatsHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "taskID")
+	tID, _ := uuid.Parse(taskID)
+	s, ok := a.Manager.GetTaskStat(tID)
+	if !ok {
+		msg := fmt.Sprintf("No stats collected for task %v", tID)
+		log.Println(msg)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(s)
+}
+
+func (a *Api) ApplyServiceHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	s := service.Service{}
+	err := d.Decode(&s)
+	if err == nil {
+		err = a.Manager.ApplyService(s)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error applying service: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(201)
+	json.NewEncoder(w).Encode(s)
+}
+
+func (a *Api) GetServicesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Manager.GetServices())
+}
+
+func (a *Api) GetServiceHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "serviceName")
+	s, err := a.Manager.GetService(name)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(s)
+}
+
+// ScaleServiceRequest is the body of POST /services/{serviceName}/scale.
+type ScaleServiceRequest struct {
+	Replicas int
+}
+
+func (a *Api) ScaleServiceHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "serviceName")
+	d := json.NewDecoder(r.Body)
+
+	req := ScaleServiceRequest{}
+	err := d.Decode(&req)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	err = a.Manager.ScaleService(name, req.Replicas)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (a *Api) DeleteServiceHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "serviceName")
+	err := a.Manager.DeleteService(name)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (a *Api) ApplyAutoscalerHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	h := service.HorizontalAutoscaler{}
+	err := d.Decode(&h)
+	if err == nil {
+		err = a.Manager.ApplyAutoscaler(h)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error applying autoscaler: %v\n", err)
+		log.Printf(msg)
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        msg,
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.WriteHeader(201)
+	json.NewEncoder(w).Encode(h)
+}
+
+func (a *Api) GetAutoscalersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Manager.GetAutoscalers())
+}
+
+func (a *Api) DeleteAutoscalerHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "autoscalerName")
+	err := a.Manager.DeleteAutoscaler(name)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -3,26 +3,40 @@ package manager
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ahmadateya/my-own-k8s/node"
 	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/ahmadateya/my-own-k8s/service"
+	"github.com/ahmadateya/my-own-k8s/stats"
 	"github.com/ahmadateya/my-own-k8s/store"
 	"github.com/ahmadateya/my-own-k8s/task"
 	"github.com/ahmadateya/my-own-k8s/worker"
-	"github.com/docker/go-connections/nat"
-	"github.com/golang-collections/collections/queue"
 	"github.com/google/uuid"
 	"log"
 	"log/slog"
 	"net/http"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 )
 
+// TaskResyncInterval is how often UpdateTasks polls the workers for their tasks.
+const TaskResyncInterval = 30 * time.Second
+
+// workerClient sends tasks to the workers and stops them. Those requests are made while holding
+// the manager's lock, so they must not hang on an unresponsive worker.
+var workerClient = &http.Client{Timeout: 10 * time.Second}
+
 type WorkerAddress string // <hostname>:<port>
 
 type Manager struct {
-	Pending       queue.Queue // Pending tasks (stored as task.Event)
+	// mu guards the pending queue, the worker bookkeeping maps, the nodes, the task stats, the
+	// services and the autoscalers. The API handlers and the manager's loops run concurrently, so
+	// exported methods take it and unexported ones expect it to be held.
+	mu sync.Mutex
+
+	Pending       *PendingQueue // Pending task events, highest priority first
 	TaskDb        store.Store
 	EventDb       store.Store
 	Workers       []WorkerAddress
@@ -31,7 +45,19 @@ type Manager struct {
 	LastWorker    int
 
 	WorkerNodes []*node.Node
+	nodeLabels  map[string]map[string]string // [nodeName]labels set with SetLabels, empty for removed ones
 	Scheduler   scheduler.Scheduler
+	TaskStats   map[uuid.UUID]*stats.TaskStats // latest resource usage of running tasks
+
+	GangTimeout         time.Duration
+	NodeGracePeriod     time.Duration
+	NodeEvictionTimeout time.Duration
+	gangs               map[string]*gang // [job]gang
+
+	Services    map[string]*service.Service
+	Autoscalers map[string]*service.HorizontalAutoscaler
+	terminating map[uuid.UUID]bool // service replicas being stopped to scale down
+	draining    map[uuid.UUID]bool // tasks stopped by Drain, rescheduled once their worker reports them stopped
 }
 
 func New(workers []WorkerAddress, schedulerType string, dbType string) *Manager {
@@ -47,23 +73,25 @@ func New(workers []WorkerAddress, schedulerType string, dbType string) *Manager
 		nodes = append(nodes, n)
 	}
 
-	var s scheduler.Scheduler
-	switch schedulerType {
-	case "roundrobin":
-		s = &scheduler.RoundRobin{Name: "roundrobin"}
-	case "epvm":
-		s = &scheduler.Epvm{Name: "epvm"}
-	default:
-		s = &scheduler.RoundRobin{Name: "roundrobin"}
-	}
+	s := scheduler.New(schedulerType)
 
 	m := Manager{
-		Pending:       *queue.New(),
-		Workers:       workers,
-		WorkerTaskMap: workerTaskMap,
-		TaskWorkerMap: taskWorkerMap,
-		WorkerNodes:   nodes,
-		Scheduler:     s,
+		Pending:             NewPendingQueue(),
+		Workers:             workers,
+		WorkerTaskMap:       workerTaskMap,
+		TaskWorkerMap:       taskWorkerMap,
+		WorkerNodes:         nodes,
+		nodeLabels:          make(map[string]map[string]string),
+		Scheduler:           s,
+		TaskStats:           make(map[uuid.UUID]*stats.TaskStats),
+		GangTimeout:         DefaultGangTimeout,
+		NodeGracePeriod:     DefaultNodeGracePeriod,
+		NodeEvictionTimeout: DefaultNodeEvictionTimeout,
+		gangs:               make(map[string]*gang),
+		Services:            make(map[string]*service.Service),
+		Autoscalers:         make(map[string]*service.HorizontalAutoscaler),
+		terminating:         make(map[uuid.UUID]bool),
+		draining:            make(map[uuid.UUID]bool),
 	}
 
 	var ts store.Store
@@ -88,78 +116,238 @@ func New(workers []WorkerAddress, schedulerType string, dbType string) *Manager
 }
 
 func (m *Manager) AddTask(te task.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.Pending.Enqueue(te)
 }
 
 func (m *Manager) SelectWorker(t task.Task) (*node.Node, error) {
-	candidates := m.Scheduler.SelectCandidateNodes(t, m.WorkerNodes)
-	if candidates == nil {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.selectWorker(t)
+}
+
+func (m *Manager) selectWorker(t task.Task) (*node.Node, error) {
+	start := time.Now()
+	defer func() {
+		schedulingDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	candidates := m.Scheduler.SelectCandidateNodes(t, m.schedulableNodes())
+	// not every scheduler filters on resources, and a task that fits nowhere may preempt others
+	candidates = slices.DeleteFunc(candidates, func(n *node.Node) bool {
+		return !scheduler.HasCapacity(t, n)
+	})
+	if len(candidates) == 0 {
+		schedulingFailures.Inc()
 		msg := fmt.Sprintf("No available candidates match resource request for task %v", t.ID)
 		err := fmt.Errorf(msg)
 		return nil, err
 	}
-	scores := m.Scheduler.Score(t, candidates)
+	scores, err := scheduler.Score(m.Scheduler, t, candidates)
+	if err != nil {
+		schedulingFailures.Inc()
+		return nil, fmt.Errorf("scheduler could not score nodes for task %v: %v", t.ID, err)
+	}
 	selectedNode := m.Scheduler.Pick(scores, candidates)
+	if selectedNode == nil {
+		schedulingFailures.Inc()
+		return nil, fmt.Errorf("scheduler did not pick a node for task %v", t.ID)
+	}
 	return selectedNode, nil
 }
 
+// ExplainSchedule reports how the scheduler would place t on the current worker nodes without placing it.
+func (m *Manager) ExplainSchedule(t task.Task) scheduler.Explanation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return scheduler.Explain(m.Scheduler, t, m.schedulableNodes())
+}
+
+// preempt stops lower-priority tasks on a node so that t fits there on its next pass through the
+// pending queue. The victims are requeued. It gives up at the first victim the worker doesn't
+// accept the stop of, leaving that victim where it is, and reports whether all victims were stopped.
+func (m *Manager) preempt(t task.Task) bool {
+	tasks := make(map[string][]*task.Task)
+	for w, ids := range m.WorkerTaskMap {
+		for _, id := range ids {
+			result, err := m.TaskDb.Get(id.String())
+			if err != nil {
+				log.Printf("[manager] %s\n", err)
+				continue
+			}
+			placed, ok := result.(*task.Task)
+			if !ok {
+				log.Printf("cannot convert result %v to task.Task type\n", result)
+				continue
+			}
+			if placed.State == task.Scheduled || placed.State == task.Running {
+				tasks[string(w)] = append(tasks[string(w)], placed)
+			}
+		}
+	}
+
+	n, victims := scheduler.SelectVictims(t, m.schedulableNodes(), tasks)
+	if n == nil {
+		return false
+	}
+
+	for _, v := range victims {
+		log.Printf("[manager] preempting task %s (priority %d) on node %s for task %s (priority %d)\n",
+			v.ID, v.Priority(), n.Name, t.ID, t.Priority())
+		err := m.stopTask(WorkerAddress(n.Name), v.ID.String())
+		if err != nil {
+			// the victim keeps running and holding its resources, so t doesn't fit after all
+			log.Printf("[manager] unable to preempt task %s, giving up on preemption for task %s: %v\n", v.ID, t.ID, err)
+			return false
+		}
+		m.rescheduleTask(v, task.Pending)
+	}
+	return true
+}
+
+// UpdateTasks periodically resyncs the tasks with the workers. Registered workers push changes
+// as they happen, so this only catches up on pushes that were lost. It also counts as contact
+// with workers that don't send heartbeats, hence the interval is shorter than the node grace period.
 func (m *Manager) UpdateTasks() {
 	for {
 		log.Println("Checking for task updates from workers")
 		m.updateTasks()
 		log.Println("Task updates completed")
-		log.Println("Sleeping for 15 seconds")
-		time.Sleep(15 * time.Second)
+		log.Printf("Sleeping for %v\n", TaskResyncInterval)
+		time.Sleep(TaskResyncInterval)
 	}
 }
 
 // updateTasks => Query the worker to get a list of its tasks, and foreach task update its state in the manager’s DB
 func (m *Manager) updateTasks() {
-	for _, w := range m.Workers {
+	m.mu.Lock()
+	workers := append([]WorkerAddress{}, m.Workers...)
+	m.mu.Unlock()
+
+	for _, w := range workers {
 		slog.Info(fmt.Sprintf("Checking worker %v for task updates", w))
-		url := fmt.Sprintf("http://%s/tasks", w)
-		resp, err := http.Get(url)
+		tasks, err := getWorkerTasks(w)
 		if err != nil {
-			slog.Info(fmt.Sprintf("Error connecting to %v: %v\n", w, err))
+			slog.Info(err.Error())
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			slog.Info(fmt.Sprintf("Error sending request: %v\n", err))
+		m.mu.Lock()
+		if n := m.getNode(string(w)); n != nil {
+			// an unreachable node is left to the node monitor, which marks it Unknown once it
+			// has been unreachable for too long
+			switch {
+			case err == nil:
+				n.MarkContact()
+			case !errors.Is(err, errWorkerUnreachable):
+				n.SetCondition(node.NotReady)
+			}
 		}
-
-		d := json.NewDecoder(resp.Body)
-		var tasks []*task.Task
-		err = d.Decode(&tasks)
-		if err != nil {
-			slog.Info(fmt.Sprintf("Error unmarshalling tasks: %s\n", err.Error()))
+		for _, t := range tasks {
+			m.updateTask(w, t)
 		}
+		m.mu.Unlock()
+	}
+}
 
-		for _, t := range tasks {
-			log.Printf("[manager] Attempting to update task %v", t.ID)
+var errWorkerUnreachable = errors.New("worker unreachable")
 
-			result, err := m.TaskDb.Get(t.ID.String())
-			if err != nil {
-				log.Printf("[manager] %s\n", err)
-				continue
-			}
-			taskPersisted, ok := result.(*task.Task)
-			if !ok {
-				log.Printf("cannot convert result %v to task.Task type\n", result)
-				continue
-			}
+// getWorkerTasks lists the tasks of worker w. The error wraps errWorkerUnreachable if the worker
+// couldn't be reached at all.
+func getWorkerTasks(w WorkerAddress) ([]*task.Task, error) {
+	url := fmt.Sprintf("http://%s/tasks", w)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %w: %v", w, errWorkerUnreachable, err)
+	}
+	defer resp.Body.Close()
 
-			if taskPersisted.State != t.State {
-				taskPersisted.State = t.State
-			}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting tasks from %v: status %d", w, resp.StatusCode)
+	}
 
-			taskPersisted.StartTime = t.StartTime
-			taskPersisted.FinishTime = t.FinishTime
-			taskPersisted.ContainerID = t.ContainerID
-			taskPersisted.HostPorts = t.HostPorts
+	var tasks []*task.Task
+	err = json.NewDecoder(resp.Body).Decode(&tasks)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling tasks: %v", err)
+	}
+	return tasks, nil
+}
+
+// ReportTaskStatus applies the task updates pushed by a worker.
+func (m *Manager) ReportTaskStatus(report node.TaskStatusReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := WorkerAddress(report.Address)
+	n := m.getNode(report.Address)
+	if n == nil {
+		return fmt.Errorf("node %s is not registered", report.Address)
+	}
+	n.MarkContact()
+	for i := range report.Tasks {
+		m.updateTask(w, &report.Tasks[i])
+	}
+	return nil
+}
+
+// updateTask records the state of a task as reported by worker w, either polled by updateTasks or
+// pushed by the worker. Reports older than the last one applied are ignored.
+func (m *Manager) updateTask(w WorkerAddress, t *task.Task) {
+	// a task that was evicted or rescheduled is still listed by its previous worker
+	if m.TaskWorkerMap[t.ID] != w {
+		m.stopOrphanedTask(w, t)
+		return
+	}
+	log.Printf("[manager] Attempting to update task %v", t.ID)
 
-			m.TaskDb.Put(taskPersisted.ID.String(), taskPersisted)
+	result, err := m.TaskDb.Get(t.ID.String())
+	if err != nil {
+		log.Printf("[manager] %s\n", err)
+		return
+	}
+	taskPersisted, ok := result.(*task.Task)
+	if !ok {
+		log.Printf("cannot convert result %v to task.Task type\n", result)
+		return
+	}
+	if t.ResourceVersion != 0 && t.ResourceVersion <= taskPersisted.ResourceVersion {
+		return
+	}
+
+	if t.State == task.Evicted && (taskPersisted.State == task.Scheduled || taskPersisted.State == task.Running) {
+		log.Printf("[manager] task %s was evicted by worker %s, rescheduling\n", t.ID, w)
+		m.rescheduleTask(taskPersisted, task.Evicted)
+		return
+	}
+
+	if m.draining[t.ID] && t.State.Terminal() {
+		log.Printf("[manager] task %s stopped on drained worker %s, rescheduling\n", t.ID, w)
+		m.rescheduleTask(taskPersisted, task.Pending)
+		return
+	}
+
+	if taskPersisted.State != t.State {
+		// a finished task gives its resources back exactly once, whether it completed or failed
+		if t.State.Terminal() && !taskPersisted.State.Terminal() {
+			m.releaseResources(w, taskPersisted)
 		}
+		taskPersisted.State = t.State
 	}
+
+	taskPersisted.StartTime = t.StartTime
+	taskPersisted.FinishTime = t.FinishTime
+	taskPersisted.ContainerID = t.ContainerID
+	taskPersisted.HostPorts = t.HostPorts
+	taskPersisted.Ready = t.Ready
+	taskPersisted.Probes = t.Probes
+	taskPersisted.RestartCount = t.RestartCount
+	taskPersisted.ExitCode = t.ExitCode
+	taskPersisted.ExitReason = t.ExitReason
+	taskPersisted.RestartAt = t.RestartAt
+	taskPersisted.ResourceVersion = t.ResourceVersion
+
+	m.TaskDb.Put(taskPersisted.ID.String(), taskPersisted)
 }
 
 func (m *Manager) ProcessTasks() {
@@ -172,26 +360,21 @@ func (m *Manager) ProcessTasks() {
 }
 
 func (m *Manager) GetTasks() []*task.Task {
-	tasks := []*task.Task{}
-	for _, t := range tasks {
-		if t.State == task.Running && t.RestartCount < 3 {
-			err := m.checkTaskHealth(*t)
-			if err != nil {
-				if t.RestartCount < 3 {
-					m.restartTask(t)
-				}
-			}
-		} else if t.State == task.Failed && t.RestartCount < 3 {
-			m.restartTask(t)
-		}
+	taskList, err := m.TaskDb.List()
+	if err != nil {
+		log.Printf("error getting list of tasks: %v\n", err)
+		return nil
 	}
-	return tasks
+
+	return taskList.([]*task.Task)
 }
 
 func (m *Manager) SendWork() {
-	if m.Pending.Len() > 0 {
-		e := m.Pending.Dequeue()
-		te := e.(task.Event)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scheduleGangs()
+	if te, ok := m.Pending.Dequeue(); ok {
 		err := m.EventDb.Put(te.ID.String(), &te)
 		if err != nil {
 			log.Printf("error attempting to store task event %s: %s\n", te.ID.String(), err)
@@ -221,201 +404,144 @@ func (m *Manager) SendWork() {
 			return
 		}
 
-		t := te.Task
-		w, err := m.SelectWorker(t)
-		if err != nil {
-			log.Printf("error selecting worker for task %s: %v\n", t.ID, err)
+		result, err := m.TaskDb.Get(te.Task.ID.String())
+		if persistedTask, ok := result.(*task.Task); err == nil && ok && (persistedTask.State == task.Completed || persistedTask.State == task.Failed) {
+			log.Printf("[manager] task %s was stopped while pending, dropping it\n", te.Task.ID)
+			m.Pending.Forget(te.Task.ID)
 			return
 		}
 
-		log.Printf("[manager] selected worker %s for task %s\n", w.Name, t.ID)
-
-		m.WorkerTaskMap[WorkerAddress(w.Name)] = append(m.WorkerTaskMap[WorkerAddress(w.Name)], te.Task.ID)
-		m.TaskWorkerMap[t.ID] = WorkerAddress(w.Name)
-
-		t.State = task.Scheduled
-		m.TaskDb.Put(t.ID.String(), &t)
-
-		data, err := json.Marshal(te)
-		if err != nil {
-			log.Printf("Unable to marshal task object: %v.\n", t)
-		}
-
-		url := fmt.Sprintf("http://%s/tasks", w.Name)
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-		if err != nil {
-			log.Printf("[manager] Error connecting to %v: %v\n", w, err)
-			m.Pending.Enqueue(t)
+		if te.Task.GangSize > 1 {
+			m.addToGang(te)
 			return
 		}
 
-		d := json.NewDecoder(resp.Body)
-		if resp.StatusCode != http.StatusCreated {
-			e := worker.ErrResponse{}
-			err := d.Decode(&e)
-			if err != nil {
-				fmt.Printf("Error decoding response: %s\n", err.Error())
+		t := te.Task
+		w, err := m.selectWorker(t)
+		if err != nil {
+			log.Printf("error selecting worker for task %s: %v\n", t.ID, err)
+			// the task stays pending until it can be placed
+			if m.preempt(t) {
+				m.Pending.Enqueue(te)
 				return
 			}
-			log.Printf("Response error (%d): %s\n", e.HTTPStatusCode, e.Message)
+			delay := m.Pending.Backoff(te)
+			log.Printf("[manager] no worker for task %s yet, retrying in %v\n", t.ID, delay)
 			return
 		}
 
-		t = task.Task{}
-		err = d.Decode(&t)
+		log.Printf("[manager] selected worker %s for task %s\n", w.Name, t.ID)
+		m.Pending.Forget(t.ID)
+		allocate(w, t)
+		err = m.sendToWorker(te, w)
 		if err != nil {
-			fmt.Printf("Error decoding response: %s\n", err.Error())
-			return
+			log.Printf("[manager] %v\n", err)
+			m.Pending.Backoff(te)
 		}
-		w.TaskCount++
-		log.Printf("[manager] received response from worker: %#v\n", t)
 	} else {
 		log.Println("No work in the queue")
 	}
 }
 
-func (m *Manager) checkTaskHealth(t task.Task) error {
-	log.Printf("Calling health check for task %s: %s\n", t.ID, t.HealthCheck)
-
-	w := m.TaskWorkerMap[t.ID]
-	hostPort := getHostPort(t.HostPorts)
-	workerAddr := strings.Split(string(w), ":")
-	if hostPort == nil {
-		log.Printf("Have not collected task %s host port yet. Skipping.\n", t.ID)
-		return nil
-	}
-	url := fmt.Sprintf("http://%s:%s%s", workerAddr[0], *hostPort, t.HealthCheck)
-	log.Printf("Calling health check for task %s: %s\n", t.ID, url)
-	resp, err := http.Get(url)
-	if err != nil {
-		msg := fmt.Sprintf("Error connecting to health check %s", url)
-		log.Println(msg)
-		return fmt.Errorf(msg)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("Error health check for task %s did not return 200\n", t.ID)
-		log.Println(msg)
-		return fmt.Errorf(msg)
-	}
-
-	log.Printf("Task %s health check response: %v\n", t.ID, resp.StatusCode)
+// sendToWorker assigns the task to the worker and sends it the task event. The task's resources
+// must already be allocated on the worker's node. If the worker doesn't accept the task, the
+// task is unassigned again and left for the caller to requeue.
+func (m *Manager) sendToWorker(te task.Event, w *node.Node) error {
+	t := te.Task
+	m.WorkerTaskMap[WorkerAddress(w.Name)] = append(m.WorkerTaskMap[WorkerAddress(w.Name)], te.Task.ID)
+	m.TaskWorkerMap[t.ID] = WorkerAddress(w.Name)
 
-	return nil
-}
+	t.State = task.Scheduled
+	// the worker numbers the task's versions on its own
+	t.ResourceVersion = 0
+	m.TaskDb.Put(t.ID.String(), &t)
 
-func getHostPort(ports nat.PortMap) *string {
-	for k, _ := range ports {
-		return &ports[k][0].HostPort
+	err := postTask(te, w)
+	if err != nil {
+		m.unassignTask(&t)
+		t.State = task.Pending
+		m.TaskDb.Put(t.ID.String(), &t)
+		return fmt.Errorf("error sending task %s to worker %s: %v", t.ID, w.Name, err)
 	}
 	return nil
 }
 
-func (m *Manager) doHealthChecks() {
-	for _, t := range m.GetTasks() {
-		if t.State == task.Running && t.RestartCount < 3 {
-			err := m.checkTaskHealth(*t)
-			if err != nil {
-				if t.RestartCount < 3 {
-					m.restartTask(t)
-				}
-			}
-		} else if t.State == task.Failed && t.RestartCount < 3 {
-			m.restartTask(t)
-		}
-	}
-}
-
-func (m *Manager) restartTask(t *task.Task) {
-	// Get the worker where the task was running
-	w := m.TaskWorkerMap[t.ID]
-	t.State = task.Scheduled
-	t.RestartCount++
-	// We need to overwrite the existing task to ensure it has
-	// the current state
-	m.TaskDb.Put(t.ID.String(), t)
-
-	te := task.Event{
-		ID:        uuid.New(),
-		State:     task.Running,
-		Timestamp: time.Now(),
-		Task:      *t,
-	}
+func postTask(te task.Event, w *node.Node) error {
 	data, err := json.Marshal(te)
 	if err != nil {
-		log.Printf("Unable to marshal task object: %v.", t)
+		return fmt.Errorf("unable to marshal task event: %v", err)
 	}
 
-	url := fmt.Sprintf("http://%s/tasks", w)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	url := fmt.Sprintf("http://%s/tasks", w.Name)
+	resp, err := workerClient.Post(url, "application/json", bytes.NewBuffer(data))
 	if err != nil {
-		log.Printf("[manager] Error connecting to %v: %v", w, err)
-		m.Pending.Enqueue(t)
-		return
+		return err
 	}
+	defer resp.Body.Close()
 
 	d := json.NewDecoder(resp.Body)
 	if resp.StatusCode != http.StatusCreated {
 		e := worker.ErrResponse{}
 		err := d.Decode(&e)
 		if err != nil {
-			fmt.Printf("Error decoding response: %s\n", err.Error())
-			return
+			return fmt.Errorf("worker returned status %d", resp.StatusCode)
 		}
-		log.Printf("Response error (%d): %s", e.HTTPStatusCode, e.Message)
-		return
+		return fmt.Errorf("response error (%d): %s", e.HTTPStatusCode, e.Message)
 	}
 
-	newTask := task.Task{}
-	err = d.Decode(&newTask)
+	t := task.Task{}
+	err = d.Decode(&t)
 	if err != nil {
+		// the worker accepted the task all the same
 		fmt.Printf("Error decoding response: %s\n", err.Error())
-		return
-	}
-	log.Printf("[manager] response from worker: %#v\n", t)
-}
-
-func (m *Manager) DoHealthChecks() {
-	for {
-		log.Println("Performing task health check")
-		m.doHealthChecks()
-		log.Println("Task health checks completed")
-		log.Println("Sleeping for 60 seconds")
-		time.Sleep(60 * time.Second)
+		return nil
 	}
+	log.Printf("[manager] received response from worker: %#v\n", t)
+	return nil
 }
 
-func (m *Manager) stopTask(worker WorkerAddress, taskID string) {
-	client := &http.Client{}
+// stopTask asks the worker to stop the task. The worker stops it in the background and reports
+// the task's final state once it is stopped.
+func (m *Manager) stopTask(worker WorkerAddress, taskID string) error {
 	url := fmt.Sprintf("http://%s/tasks/%s", string(worker), taskID)
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		log.Printf("error creating request to delete task %s: %v\n", taskID, err)
-		return
+		return err
 	}
 
-	resp, err := client.Do(req)
+	resp, err := workerClient.Do(req)
 	if err != nil {
 		log.Printf("error connecting to worker at %s: %v\n", url, err)
-		return
+		return err
 	}
+	resp.Body.Close()
 
 	if resp.StatusCode != 204 {
-		log.Printf("Error sending request: %v\n", err)
-		return
+		log.Printf("Error sending request to delete task %s: status %d\n", taskID, resp.StatusCode)
+		return fmt.Errorf("worker %s returned status %d", worker, resp.StatusCode)
 	}
 
 	log.Printf("task %s has been scheduled to be stopped", taskID)
+	return nil
 }
 
 func (m *Manager) UpdateNodeStats() {
 	for {
-		for _, node := range m.WorkerNodes {
-			log.Printf("Collecting stats for node %v", node.Name)
-			_, err := node.GetStats()
+		m.mu.Lock()
+		nodes := append([]*node.Node{}, m.WorkerNodes...)
+		m.mu.Unlock()
+
+		for _, n := range nodes {
+			log.Printf("Collecting stats for node %v", n.Name)
+			s, trend, err := n.FetchStats()
 			if err != nil {
 				log.Printf("error updating node stats: %v", err)
+				continue
 			}
+			m.mu.Lock()
+			n.SetStats(*s, trend)
+			m.mu.Unlock()
 		}
 		time.Sleep(15 * time.Second)
 	}
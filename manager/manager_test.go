@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+func TestReportTaskStatusIgnoresStaleVersions(t *testing.T) {
+	f := newFakeWorker(t)
+	m := New([]WorkerAddress{f.address()}, "roundrobin", "memory")
+	tk := &task.Task{ID: uuid.New(), State: task.Scheduled}
+	place(m, f.address(), tk)
+
+	report := func(state task.State, version uint64) {
+		t.Helper()
+		err := m.ReportTaskStatus(node.TaskStatusReport{
+			Address: string(f.address()),
+			Tasks:   []task.Task{{ID: tk.ID, State: state, ResourceVersion: version}},
+		})
+		if err != nil {
+			t.Fatalf("ReportTaskStatus() error = %v", err)
+		}
+	}
+
+	report(task.Running, 1)
+	report(task.Completed, 2)
+	// the push of version 1 arrives late, after the worker was polled for version 2
+	report(task.Running, 1)
+	report(task.Running, 2)
+
+	result, err := m.TaskDb.Get(tk.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := result.(*task.Task)
+	if got.State != task.Completed || got.ResourceVersion != 2 {
+		t.Errorf("task is %s at version %d, want Completed at version 2", got.State, got.ResourceVersion)
+	}
+}
+
+func TestReportTaskStatusFromUnregisteredNode(t *testing.T) {
+	m := New([]WorkerAddress{"localhost:5556"}, "roundrobin", "memory")
+	err := m.ReportTaskStatus(node.TaskStatusReport{Address: "localhost:5557"})
+	if err == nil {
+		t.Error("ReportTaskStatus() from an unregistered node should return an error")
+	}
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"github.com/ahmadateya/my-own-k8s/metrics"
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+var (
+	registry    = metrics.NewRegistry()
+	httpMetrics = metrics.NewHTTPMetrics(registry, "cube_manager")
+
+	nodeStats           = metrics.NewStatsGauges(registry, "cube_node", "node")
+	nodeCpuTrend        = registry.NewGaugeVec("cube_node_cpu_usage_avg_ratio", "Average share of CPU time spent non-idle over the node's stats trend window.", "node")
+	nodeMemoryAllocated = registry.NewGaugeVec("cube_node_memory_allocated_bytes", "Memory requested by the tasks placed on the node.", "node")
+	nodeDiskAllocated   = registry.NewGaugeVec("cube_node_disk_allocated_bytes", "Disk requested by the tasks placed on the node.", "node")
+	nodeTasks           = registry.NewGaugeVec("cube_node_tasks", "Number of tasks placed on the node.", "node")
+	nodeCondition       = registry.NewGaugeVec("cube_node_condition", "1 for the node's current condition, 0 otherwise.", "node", "condition")
+	nodeUnschedulable   = registry.NewGaugeVec("cube_node_unschedulable", "1 if the node is cordoned.", "node")
+
+	tasksByState = registry.NewGaugeVec("cube_tasks", "Number of tasks known to the manager by state.", "state")
+	pendingTasks = registry.NewGaugeVec("cube_pending_tasks", "Number of task events waiting in the manager's pending queue.")
+
+	schedulingDuration = registry.NewHistogramVec("cube_scheduling_duration_seconds", "Time taken to select a worker for a task.", metrics.DefBuckets)
+	schedulingFailures = registry.NewCounterVec("cube_scheduling_failures_total", "Number of times no worker could be selected for a task.")
+)
+
+// updateMetrics refreshes the gauges that mirror the manager's state right before a scrape.
+func (m *Manager) updateMetrics() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nodeStats.Reset()
+	nodeCpuTrend.Reset()
+	nodeMemoryAllocated.Reset()
+	nodeDiskAllocated.Reset()
+	nodeTasks.Reset()
+	nodeCondition.Reset()
+	nodeUnschedulable.Reset()
+	for _, n := range m.WorkerNodes {
+		nodeStats.Set(&n.Stats, n.Name)
+		if usage, err := n.CpuUsage(); err == nil {
+			nodeCpuTrend.Set(usage, n.Name)
+		}
+		nodeMemoryAllocated.Set(float64(n.MemoryAllocated), n.Name)
+		nodeDiskAllocated.Set(float64(n.DiskAllocated), n.Name)
+		nodeTasks.Set(float64(n.TaskCount), n.Name)
+		for _, c := range []node.Condition{node.Ready, node.NotReady, node.Unknown} {
+			nodeCondition.Set(boolToFloat(n.Condition == c), n.Name, string(c))
+		}
+		nodeUnschedulable.Set(boolToFloat(n.Unschedulable), n.Name)
+	}
+
+	tasksByState.Reset()
+	counts := make(map[task.State]int)
+	result, err := m.TaskDb.List()
+	if tasks, ok := result.([]*task.Task); err == nil && ok {
+		for _, t := range tasks {
+			counts[t.State]++
+		}
+	}
+	for _, s := range []task.State{task.Pending, task.Scheduled, task.Running, task.Completed, task.Failed, task.Lost, task.Evicted, task.CrashLoopBackOff} {
+		tasksByState.Set(float64(counts[s]), s.String())
+	}
+
+	pendingTasks.Set(float64(m.Pending.Len()))
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"log"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultNodeGracePeriod is how long a node may go without a successful poll or heartbeat
+	// before its condition becomes Unknown.
+	DefaultNodeGracePeriod = 40 * time.Second
+	// DefaultNodeEvictionTimeout is how long a node may stay NotReady or Unknown before its
+	// tasks are marked as lost and rescheduled onto healthy workers.
+	DefaultNodeEvictionTimeout = 60 * time.Second
+)
+
+func (m *Manager) MonitorNodes() {
+	for {
+		log.Println("Checking node conditions")
+		m.monitorNodes()
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (m *Manager) monitorNodes() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range m.WorkerNodes {
+		if n.Condition == node.Ready && time.Since(n.LastContact) > m.NodeGracePeriod {
+			log.Printf("[manager] node %s has not responded for %v, marking it %s\n", n.Name, time.Since(n.LastContact).Round(time.Second), node.Unknown)
+			n.SetCondition(node.Unknown)
+		}
+
+		if n.Condition != node.Ready && time.Since(n.LastTransition) > m.NodeEvictionTimeout {
+			m.rescheduleLostTasks(n)
+		}
+	}
+}
+
+// rescheduleLostTasks marks the tasks placed on an unhealthy node as lost and queues them to be
+// scheduled onto other nodes. The containers can't be stopped while the node is unreachable,
+// they are stopped by updateTasks if the node comes back.
+func (m *Manager) rescheduleLostTasks(n *node.Node) {
+	w := WorkerAddress(n.Name)
+	taskIDs := append([]uuid.UUID{}, m.WorkerTaskMap[w]...)
+	for _, id := range taskIDs {
+		result, err := m.TaskDb.Get(id.String())
+		if err != nil {
+			log.Printf("[manager] %s\n", err)
+			continue
+		}
+		t, ok := result.(*task.Task)
+		if !ok {
+			log.Printf("cannot convert result %v to task.Task type\n", result)
+			continue
+		}
+		if !onNode(t.State) {
+			continue
+		}
+
+		log.Printf("[manager] node %s is %s, rescheduling lost task %s\n", n.Name, n.Condition, t.ID)
+		m.rescheduleTask(t, task.Lost)
+	}
+}
+
+// stopOrphanedTask stops a task that is still running on a worker although the manager has
+// moved it elsewhere, e.g. after the worker came back from a failure.
+func (m *Manager) stopOrphanedTask(w WorkerAddress, t *task.Task) {
+	if t.State != task.Running && t.State != task.CrashLoopBackOff {
+		return
+	}
+
+	result, err := m.TaskDb.Get(t.ID.String())
+	if err != nil {
+		return
+	}
+	persisted, ok := result.(*task.Task)
+	if !ok {
+		return
+	}
+
+	owner, assigned := m.TaskWorkerMap[t.ID]
+	if (assigned && owner != w) || (!assigned && (persisted.State == task.Lost || persisted.State == task.Pending)) {
+		log.Printf("[manager] stopping task %s on worker %s, it has been rescheduled\n", t.ID, w)
+		m.stopTask(w, t.ID.String())
+	}
+}
+
+// schedulableNodes returns the nodes that are Ready to receive new tasks.
+func (m *Manager) schedulableNodes() []*node.Node {
+	var nodes []*node.Node
+	for _, n := range m.WorkerNodes {
+		if n.Condition == node.Ready {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+func TestMonitorNodesReschedulesTasksOfLostNodes(t *testing.T) {
+	m := New([]WorkerAddress{"worker-1:5556", "worker-2:5556"}, "roundrobin", "memory")
+	lost, healthy := m.WorkerNodes[0], m.WorkerNodes[1]
+
+	running := &task.Task{ID: uuid.New(), State: task.Running}
+	crashLooping := &task.Task{ID: uuid.New(), State: task.CrashLoopBackOff}
+	completed := &task.Task{ID: uuid.New(), State: task.Completed}
+	elsewhere := &task.Task{ID: uuid.New(), State: task.Running}
+	for _, tk := range []*task.Task{running, crashLooping, completed} {
+		place(m, "worker-1:5556", tk)
+	}
+	place(m, "worker-2:5556", elsewhere)
+
+	lost.LastContact = time.Now().Add(-time.Hour)
+	m.monitorNodes()
+	if lost.Condition != node.Unknown || healthy.Condition != node.Ready {
+		t.Fatalf("conditions = %s and %s, want Unknown for the silent node only", lost.Condition, healthy.Condition)
+	}
+	if m.Pending.Len() != 0 {
+		t.Fatal("tasks should not be rescheduled before the eviction timeout")
+	}
+
+	lost.LastTransition = time.Now().Add(-m.NodeEvictionTimeout - time.Second)
+	m.monitorNodes()
+	for _, tk := range []*task.Task{running, crashLooping} {
+		if taskState(m, tk.ID) != task.Lost {
+			t.Errorf("task on the lost node is %s, want Lost", taskState(m, tk.ID))
+		}
+		if _, ok := m.TaskWorkerMap[tk.ID]; ok {
+			t.Error("task on the lost node should no longer be assigned to it")
+		}
+	}
+	if taskState(m, completed.ID) != task.Completed || taskState(m, elsewhere.ID) != task.Running {
+		t.Error("completed tasks and tasks on other nodes should be left alone")
+	}
+	if m.Pending.Len() != 2 {
+		t.Errorf("pending queue has %d events, want the 2 lost tasks", m.Pending.Len())
+	}
+	if lost.TaskCount != 1 {
+		t.Errorf("lost node counts %d tasks, want only the completed one", lost.TaskCount)
+	}
+}
+
+func TestMarkContactRecoversNode(t *testing.T) {
+	m := New([]WorkerAddress{"worker-1:5556"}, "roundrobin", "memory")
+	n := m.WorkerNodes[0]
+	n.SetCondition(node.NotReady)
+	if len(m.schedulableNodes()) != 0 {
+		t.Fatal("a NotReady node should not be schedulable")
+	}
+
+	n.MarkContact()
+	m.monitorNodes()
+	if n.Condition != node.Ready || len(m.schedulableNodes()) != 1 {
+		t.Errorf("node is %s after contact, want Ready and schedulable", n.Condition)
+	}
+}
@@ -0,0 +1,368 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+func (m *Manager) getNode(name string) *node.Node {
+	for _, n := range m.WorkerNodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// GetNodes returns copies of the worker nodes, which the caller may read without holding the lock.
+func (m *Manager) GetNodes() []*node.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nodes := []*node.Node{}
+	for _, n := range m.WorkerNodes {
+		nodes = append(nodes, n.Copy())
+	}
+	return nodes
+}
+
+func (m *Manager) GetNode(name string) (*node.Node, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.getNode(name)
+	if n == nil {
+		return nil, fmt.Errorf("node %s not found", name)
+	}
+	return n.Copy(), nil
+}
+
+// AddTaint taints the node and, for NoExecute taints, evicts the tasks on it that do not tolerate the taint.
+func (m *Manager) AddTaint(nodeName string, taint node.Taint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+
+	n.AddTaint(taint)
+	log.Printf("[manager] added taint %s=%s:%s to node %s\n", taint.Key, taint.Value, taint.Effect, n.Name)
+
+	if taint.Effect == node.NoExecute {
+		m.evictIntolerantTasks(n, taint)
+	}
+	return nil
+}
+
+func (m *Manager) RemoveTaint(nodeName string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+
+	if !n.RemoveTaint(key) {
+		return fmt.Errorf("node %s has no taint with key %s", nodeName, key)
+	}
+	log.Printf("[manager] removed taint %s from node %s\n", key, n.Name)
+	return nil
+}
+
+// SetLabels merges the labels into the node's labels; an empty value removes the label. The
+// changes take precedence over the labels the worker registers with, also when it registers again.
+func (m *Manager) SetLabels(nodeName string, labels map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+
+	if m.nodeLabels[nodeName] == nil {
+		m.nodeLabels[nodeName] = make(map[string]string)
+	}
+	for k, v := range labels {
+		m.nodeLabels[nodeName][k] = v
+	}
+	applyLabels(n, labels)
+	log.Printf("[manager] updated labels of node %s: %v\n", n.Name, n.Labels)
+	return nil
+}
+
+func (m *Manager) evictIntolerantTasks(n *node.Node, taint node.Taint) {
+	w := WorkerAddress(n.Name)
+	// copy the IDs since rescheduling a task removes it from the worker's list
+	taskIDs := append([]uuid.UUID{}, m.WorkerTaskMap[w]...)
+	for _, id := range taskIDs {
+		result, err := m.TaskDb.Get(id.String())
+		if err != nil {
+			log.Printf("[manager] %s\n", err)
+			continue
+		}
+		t, ok := result.(*task.Task)
+		if !ok {
+			log.Printf("cannot convert result %v to task.Task type\n", result)
+			continue
+		}
+
+		if !onNode(t.State) {
+			continue
+		}
+		if scheduler.ToleratesTaint(*t, taint) {
+			continue
+		}
+
+		log.Printf("[manager] evicting task %s from node %s: taint %s:%s not tolerated\n", t.ID, n.Name, taint.Key, taint.Effect)
+		m.stopTask(w, t.ID.String())
+		m.rescheduleTask(t, task.Pending)
+	}
+}
+
+// onNode reports whether a task in the given state occupies its node: it is scheduled there,
+// running, or waiting there for its container to be restarted.
+func onNode(s task.State) bool {
+	return s == task.Scheduled || s == task.Running || s == task.CrashLoopBackOff
+}
+
+// applyLabels merges the labels into the node's labels; an empty value removes the label.
+func applyLabels(n *node.Node, labels map[string]string) {
+	if n.Labels == nil {
+		n.Labels = make(map[string]string)
+	}
+	for k, v := range labels {
+		if v == "" {
+			delete(n.Labels, k)
+			continue
+		}
+		n.Labels[k] = v
+	}
+}
+
+// rescheduleTask detaches the task from its current worker and puts it back on the pending queue
+// so the scheduler can place it on another node. Until then the task is stored in the given state.
+func (m *Manager) rescheduleTask(t *task.Task, state task.State) {
+	m.unassignTask(t)
+
+	t.State = state
+	t.ContainerID = ""
+	t.HostPorts = nil
+	t.StartTime = time.Time{}
+	t.FinishTime = time.Time{}
+	// the next worker numbers its versions independently
+	t.ResourceVersion = 0
+	m.TaskDb.Put(t.ID.String(), t)
+
+	te := task.Event{
+		ID:        uuid.New(),
+		State:     task.Scheduled,
+		Timestamp: time.Now(),
+		Task:      *t,
+	}
+	te.Task.State = task.Scheduled
+	m.Pending.Enqueue(te)
+}
+
+// unassignTask removes the task from the worker bookkeeping maps and releases the resources it
+// held, unless it already released them when it finished.
+func (m *Manager) unassignTask(t *task.Task) {
+	w, ok := m.TaskWorkerMap[t.ID]
+	if !ok {
+		return
+	}
+	delete(m.TaskWorkerMap, t.ID)
+	delete(m.draining, t.ID)
+
+	var ids []uuid.UUID
+	for _, id := range m.WorkerTaskMap[w] {
+		if id != t.ID {
+			ids = append(ids, id)
+		}
+	}
+	m.WorkerTaskMap[w] = ids
+
+	if !t.State.Terminal() {
+		m.releaseResources(w, t)
+	}
+}
+
+// releaseResources gives the task's requested resources back to the node's allocatable pool.
+func (m *Manager) releaseResources(w WorkerAddress, t *task.Task) {
+	n := m.getNode(string(w))
+	if n == nil {
+		return
+	}
+	release(n, *t)
+}
+
+// allocate reserves the task's requested resources on the node.
+func allocate(n *node.Node, t task.Task) {
+	n.TaskCount++
+	n.DiskAllocated += t.Disk
+	n.MemoryAllocated += t.Memory
+}
+
+// release gives the task's requested resources back to the node.
+func release(n *node.Node, t task.Task) {
+	if n.TaskCount > 0 {
+		n.TaskCount--
+	}
+	if n.DiskAllocated >= t.Disk {
+		n.DiskAllocated -= t.Disk
+	} else {
+		n.DiskAllocated = 0
+	}
+	if n.MemoryAllocated >= t.Memory {
+		n.MemoryAllocated -= t.Memory
+	} else {
+		n.MemoryAllocated = 0
+	}
+}
+
+// RegisterNode adds a worker that announced itself to the manager, or refreshes the capacity and
+// labels of one that registered before, so capacity can be added without restarting the manager.
+func (m *Manager) RegisterNode(reg node.Registration) *node.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := WorkerAddress(reg.Address)
+	n := m.getNode(reg.Address)
+	if n == nil {
+		n = node.New(reg.Address, fmt.Sprintf("http://%v", reg.Address), "worker")
+		m.Workers = append(m.Workers, w)
+		m.WorkerNodes = append(m.WorkerNodes, n)
+		log.Printf("[manager] registered new worker %s at %s\n", reg.Name, reg.Address)
+	} else {
+		log.Printf("[manager] worker %s at %s registered again\n", reg.Name, reg.Address)
+	}
+	if _, ok := m.WorkerTaskMap[w]; !ok {
+		m.WorkerTaskMap[w] = []uuid.UUID{}
+	}
+
+	n.Memory = reg.Memory
+	n.Disk = reg.Disk
+	n.Cores = reg.Cores
+	// the worker's labels replace the ones it registered with before, while labels set with
+	// SetLabels are kept, as are the node's taints
+	n.Labels = make(map[string]string)
+	applyLabels(n, reg.Labels)
+	if reg.Name != "" {
+		n.Labels[node.WorkerNameLabel] = reg.Name
+	}
+	applyLabels(n, m.nodeLabels[reg.Address])
+	n.LastHeartbeat = time.Now()
+	n.MarkContact()
+	return n.Copy()
+}
+
+// Heartbeat records that the worker is alive. It fails for workers the manager doesn't know,
+// which tells the worker to register again.
+func (m *Manager) Heartbeat(hb node.Heartbeat) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(hb.Address)
+	if n == nil {
+		return fmt.Errorf("node %s is not registered", hb.Address)
+	}
+	n.LastHeartbeat = time.Now()
+	n.MarkContact()
+	if !slices.Equal(hb.Pressure, n.Pressure) {
+		log.Printf("[manager] node %s reports pressure conditions %v\n", n.Name, hb.Pressure)
+	}
+	n.SetPressure(hb.Pressure)
+	return nil
+}
+
+// Cordon marks the node unschedulable so no new tasks are placed on it. Tasks already running
+// on it are left alone.
+func (m *Manager) Cordon(nodeName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cordon(nodeName)
+}
+
+func (m *Manager) cordon(nodeName string) error {
+	n := m.getNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+	n.Unschedulable = true
+	log.Printf("[manager] cordoned node %s\n", n.Name)
+	return nil
+}
+
+// Uncordon returns a cordoned or drained node to service.
+func (m *Manager) Uncordon(nodeName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.getNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+	n.Unschedulable = false
+	log.Printf("[manager] uncordoned node %s\n", n.Name)
+	return nil
+}
+
+// ErrNotStopped is returned by Drain when the worker didn't accept the stop of some of the tasks.
+var ErrNotStopped = errors.New("unable to stop tasks")
+
+// Drain cordons the node, then stops each of its tasks, giving the containers their grace period
+// to exit. A task is rescheduled on another node once the worker reports it stopped, or once the
+// node is lost, so the old and the new instance never run at the same time. It returns the tasks
+// being stopped; the tasks the worker didn't accept the stop of stay on the node and are named
+// in an ErrNotStopped error.
+func (m *Manager) Drain(nodeName string) ([]*task.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.cordon(nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	w := WorkerAddress(nodeName)
+	var drained []*task.Task
+	var failed []string
+	taskIDs := append([]uuid.UUID{}, m.WorkerTaskMap[w]...)
+	for _, id := range taskIDs {
+		result, err := m.TaskDb.Get(id.String())
+		if err != nil {
+			log.Printf("[manager] %s\n", err)
+			continue
+		}
+		t, ok := result.(*task.Task)
+		if !ok {
+			log.Printf("cannot convert result %v to task.Task type\n", result)
+			continue
+		}
+		if !onNode(t.State) {
+			continue
+		}
+
+		log.Printf("[manager] draining task %s from node %s\n", t.ID, nodeName)
+		err = m.stopTask(w, t.ID.String())
+		if err != nil {
+			failed = append(failed, t.ID.String())
+			continue
+		}
+		m.draining[t.ID] = true
+		drained = append(drained, t)
+	}
+	if len(failed) > 0 {
+		return drained, fmt.Errorf("%w on node %s: %v", ErrNotStopped, nodeName, failed)
+	}
+	return drained, nil
+}
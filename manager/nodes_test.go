@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+// fakeWorker serves the worker API calls the manager makes to start and stop tasks. Start
+// requests are answered with startStatus, 201 unless set otherwise, and stop requests with
+// stopStatus, 204 unless set otherwise.
+type fakeWorker struct {
+	mu          sync.Mutex
+	startStatus int
+	stopStatus  int
+	stopped     []string // task IDs
+	srv         *httptest.Server
+}
+
+func newFakeWorker(t *testing.T) *fakeWorker {
+	f := &fakeWorker{startStatus: http.StatusCreated, stopStatus: http.StatusNoContent}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(f.startStatus)
+		case http.MethodDelete:
+			f.stopped = append(f.stopped, path.Base(r.URL.Path))
+			w.WriteHeader(f.stopStatus)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeWorker) address() WorkerAddress {
+	return WorkerAddress(strings.TrimPrefix(f.srv.URL, "http://"))
+}
+
+func (f *fakeWorker) wasStopped(id uuid.UUID) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.stopped {
+		if s == id.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// place stores the task as placed on worker w.
+func place(m *Manager, w WorkerAddress, t *task.Task) {
+	m.TaskDb.Put(t.ID.String(), t)
+	m.TaskWorkerMap[t.ID] = w
+	m.WorkerTaskMap[w] = append(m.WorkerTaskMap[w], t.ID)
+	allocate(m.getNode(string(w)), *t)
+}
+
+func taskState(m *Manager, id uuid.UUID) task.State {
+	result, err := m.TaskDb.Get(id.String())
+	if err != nil {
+		return -1
+	}
+	return result.(*task.Task).State
+}
+
+func TestNoExecuteTaintEvictsIntolerantTasks(t *testing.T) {
+	f := newFakeWorker(t)
+	m := New([]WorkerAddress{f.address()}, "roundrobin", "memory")
+
+	running := &task.Task{ID: uuid.New(), State: task.Running}
+	crashLooping := &task.Task{ID: uuid.New(), State: task.CrashLoopBackOff}
+	completed := &task.Task{ID: uuid.New(), State: task.Completed}
+	tolerating := &task.Task{ID: uuid.New(), State: task.Running, Tolerations: []task.Toleration{{Key: "maintenance", Operator: "Exists"}}}
+	for _, tk := range []*task.Task{running, crashLooping, completed, tolerating} {
+		place(m, f.address(), tk)
+	}
+
+	err := m.AddTaint(string(f.address()), node.Taint{Key: "maintenance", Effect: node.NoExecute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tk := range []*task.Task{running, crashLooping} {
+		if !f.wasStopped(tk.ID) {
+			t.Errorf("%s task should be stopped", tk.State)
+		}
+		if taskState(m, tk.ID) != task.Pending {
+			t.Errorf("evicted task is %s, want Pending", taskState(m, tk.ID))
+		}
+	}
+	if f.wasStopped(completed.ID) || f.wasStopped(tolerating.ID) {
+		t.Error("completed tasks and tasks tolerating the taint should be left alone")
+	}
+	if m.Pending.Len() != 2 {
+		t.Errorf("pending queue has %d events, want the 2 evicted tasks", m.Pending.Len())
+	}
+}
+
+func TestNoScheduleTaintKeepsRunningTasks(t *testing.T) {
+	f := newFakeWorker(t)
+	m := New([]WorkerAddress{f.address()}, "roundrobin", "memory")
+	running := &task.Task{ID: uuid.New(), State: task.Running}
+	place(m, f.address(), running)
+
+	err := m.AddTaint(string(f.address()), node.Taint{Key: "maintenance", Effect: node.NoSchedule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.wasStopped(running.ID) || taskState(m, running.ID) != task.Running {
+		t.Error("a NoSchedule taint should not evict running tasks")
+	}
+}
+
+func TestDrainReschedulesStoppedTasks(t *testing.T) {
+	f := newFakeWorker(t)
+	m := New([]WorkerAddress{f.address()}, "roundrobin", "memory")
+
+	running := &task.Task{ID: uuid.New(), State: task.Running, Memory: 100_000}
+	completed := &task.Task{ID: uuid.New(), State: task.Completed}
+	place(m, f.address(), running)
+	place(m, f.address(), completed)
+
+	drained, err := m.Drain(string(f.address()))
+	if err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if !m.WorkerNodes[0].Unschedulable {
+		t.Error("a drained node should be cordoned")
+	}
+	if len(drained) != 1 || drained[0].ID != running.ID || !f.wasStopped(running.ID) {
+		t.Fatalf("Drain() = %v, want only the running task stopped", drained)
+	}
+
+	// the task is rescheduled once the worker reports it stopped
+	m.updateTask(f.address(), &task.Task{ID: running.ID, State: task.Completed, ResourceVersion: 1})
+	if _, ok := m.TaskWorkerMap[running.ID]; ok {
+		t.Error("the drained task should no longer be assigned to the node")
+	}
+	if n := m.WorkerNodes[0]; n.MemoryAllocated != 0 {
+		t.Errorf("node has %d bytes allocated, want the drained task's memory released", n.MemoryAllocated)
+	}
+	if s := taskState(m, running.ID); s != task.Pending {
+		t.Errorf("drained task is %s, want Pending", s)
+	}
+	if te, ok := m.Pending.Dequeue(); !ok || te.Task.ID != running.ID {
+		t.Error("the drained task should be pending again")
+	}
+}
+
+func TestDrainReportsTasksTheWorkerDidNotStop(t *testing.T) {
+	f := newFakeWorker(t)
+	f.stopStatus = http.StatusInternalServerError
+	m := New([]WorkerAddress{f.address()}, "roundrobin", "memory")
+	running := &task.Task{ID: uuid.New(), State: task.Running}
+	place(m, f.address(), running)
+
+	drained, err := m.Drain(string(f.address()))
+	if !errors.Is(err, ErrNotStopped) {
+		t.Errorf("Drain() error = %v, want ErrNotStopped", err)
+	}
+	if len(drained) != 0 || m.draining[running.ID] {
+		t.Errorf("Drain() = %v, want no task drained", drained)
+	}
+
+	if _, err := m.Drain("unknown:5556"); err == nil {
+		t.Error("Drain() of an unknown node should return an error")
+	}
+}
@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPlacementBackoff is how long a task that couldn't be placed waits before the
+	// scheduler tries it again; it doubles with every failed attempt up to MaxPlacementBackoff.
+	DefaultPlacementBackoff = 10 * time.Second
+	MaxPlacementBackoff     = 5 * time.Minute
+)
+
+// PendingQueue holds the task events waiting to be sent to a worker, ordered by task priority.
+// Events with the same priority are dequeued in the order they were added. Events of tasks that
+// couldn't be placed are held back until their backoff elapses, so they don't hold up the tasks
+// behind them.
+type PendingQueue struct {
+	items    pendingHeap
+	seq      uint64
+	waiting  []waitingItem
+	attempts map[uuid.UUID]int // [taskID]failed placement attempts
+	now      func() time.Time
+}
+
+type pendingItem struct {
+	event task.Event
+	seq   uint64
+}
+
+type waitingItem struct {
+	event task.Event
+	until time.Time
+}
+
+func NewPendingQueue() *PendingQueue {
+	return &PendingQueue{
+		attempts: make(map[uuid.UUID]int),
+		now:      time.Now,
+	}
+}
+
+func (q *PendingQueue) Enqueue(te task.Event) {
+	q.seq++
+	heap.Push(&q.items, pendingItem{event: te, seq: q.seq})
+}
+
+// Backoff puts back the event of a task that couldn't be placed and returns how long it is held
+// back for.
+func (q *PendingQueue) Backoff(te task.Event) time.Duration {
+	id := te.Task.ID
+	delay := DefaultPlacementBackoff << min(q.attempts[id], 5)
+	delay = min(delay, MaxPlacementBackoff)
+	q.attempts[id]++
+	q.waiting = append(q.waiting, waitingItem{event: te, until: q.now().Add(delay)})
+	return delay
+}
+
+// Forget clears the failed placement attempts of a task, once it is placed or no longer pending.
+func (q *PendingQueue) Forget(id uuid.UUID) {
+	delete(q.attempts, id)
+}
+
+// Dequeue removes and returns the highest-priority event whose backoff has elapsed, or ok=false
+// if there is none.
+func (q *PendingQueue) Dequeue() (te task.Event, ok bool) {
+	now := q.now()
+	waiting := q.waiting[:0]
+	for _, w := range q.waiting {
+		if now.Before(w.until) {
+			waiting = append(waiting, w)
+			continue
+		}
+		q.Enqueue(w.event)
+	}
+	q.waiting = waiting
+
+	if q.items.Len() == 0 {
+		return task.Event{}, false
+	}
+	return heap.Pop(&q.items).(pendingItem).event, true
+}
+
+// Len returns the number of events in the queue, including those held back.
+func (q *PendingQueue) Len() int {
+	return q.items.Len() + len(q.waiting)
+}
+
+type pendingHeap []pendingItem
+
+func (h pendingHeap) Len() int { return len(h) }
+
+func (h pendingHeap) Less(i, j int) bool {
+	pi, pj := h[i].event.Task.Priority(), h[j].event.Task.Priority()
+	if pi != pj {
+		return pi > pj
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h pendingHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *pendingHeap) Push(x interface{}) {
+	*h = append(*h, x.(pendingItem))
+}
+
+func (h *pendingHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+func TestPendingQueueOrdersByPriority(t *testing.T) {
+	q := NewPendingQueue()
+	for _, tk := range []task.Task{
+		{Name: "batch", PriorityClass: "batch"},
+		{Name: "first", PriorityClass: ""},
+		{Name: "critical", PriorityClass: "critical"},
+		{Name: "second", PriorityClass: "normal"},
+		{Name: "unknown", PriorityClass: "unknown"},
+		{Name: "high", PriorityClass: "high"},
+	} {
+		tk.ID = uuid.New()
+		q.Enqueue(task.Event{ID: uuid.New(), Task: tk})
+	}
+	if q.Len() != 6 {
+		t.Fatalf("Len() = %d, want 6", q.Len())
+	}
+
+	// tasks with the same priority, including unknown classes, keep the order they were added in
+	want := []string{"critical", "high", "first", "second", "unknown", "batch"}
+	for _, name := range want {
+		te, ok := q.Dequeue()
+		if !ok || te.Task.Name != name {
+			t.Fatalf("Dequeue() = %s, %v, want %s", te.Task.Name, ok, name)
+		}
+	}
+	if _, ok := q.Dequeue(); ok {
+		t.Error("Dequeue() of an empty queue should report no event")
+	}
+}
+
+func TestPendingQueueBackoff(t *testing.T) {
+	q := NewPendingQueue()
+	te := task.Event{ID: uuid.New(), Task: task.Task{ID: uuid.New(), Name: "web"}}
+
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range want {
+		if got := q.Backoff(te); got != delay {
+			t.Errorf("Backoff() after %d attempts = %v, want %v", i, got, delay)
+		}
+	}
+
+	q.Forget(te.Task.ID)
+	if got := q.Backoff(te); got != DefaultPlacementBackoff {
+		t.Errorf("Backoff() after Forget = %v, want %v", got, DefaultPlacementBackoff)
+	}
+}
+
+func TestPendingQueueHoldsBackUnplacedTasks(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewPendingQueue()
+	q.now = func() time.Time { return now }
+
+	failed := task.Event{ID: uuid.New(), Task: task.Task{ID: uuid.New(), Name: "failed", PriorityClass: "critical"}}
+	q.Backoff(failed)
+	q.Enqueue(task.Event{ID: uuid.New(), Task: task.Task{ID: uuid.New(), Name: "next"}})
+	if q.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2 including the held back event", q.Len())
+	}
+
+	// the held back task doesn't block the ones behind it, despite its higher priority
+	te, ok := q.Dequeue()
+	if !ok || te.Task.Name != "next" {
+		t.Fatalf("Dequeue() = %s, %v, want next", te.Task.Name, ok)
+	}
+
+	now = now.Add(DefaultPlacementBackoff - time.Second)
+	if te, ok := q.Dequeue(); ok {
+		t.Fatalf("Dequeue() = %s before the backoff elapsed", te.Task.Name)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", q.Len())
+	}
+
+	now = now.Add(time.Second)
+	te, ok = q.Dequeue()
+	if !ok || te.Task.Name != "failed" {
+		t.Fatalf("Dequeue() = %s, %v once the backoff elapsed, want failed", te.Task.Name, ok)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", q.Len())
+	}
+}
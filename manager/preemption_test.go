@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+// newPreemptionManager returns a manager whose only worker is full with a low priority task, and
+// whose worker answers requests to stop a task with stopStatus.
+func newPreemptionManager(t *testing.T, schedulerType string, stopStatus int) (*Manager, *task.Task) {
+	f := newFakeWorker(t)
+	f.stopStatus = stopStatus
+	m := New([]WorkerAddress{f.address()}, schedulerType, "memory")
+	m.WorkerNodes[0].Memory = 1000 // KiB
+	m.WorkerNodes[0].Disk = 100
+
+	victim := &task.Task{ID: uuid.New(), Name: "batch", PriorityClass: "batch", State: task.Running, Memory: 1_000_000}
+	place(m, f.address(), victim)
+	return m, victim
+}
+
+func TestPreempt(t *testing.T) {
+	m, victim := newPreemptionManager(t, "binpack", http.StatusNoContent)
+
+	if !m.preempt(task.Task{ID: uuid.New(), PriorityClass: "high", Memory: 500_000}) {
+		t.Fatal("preempt() should stop the lower priority task")
+	}
+	if _, ok := m.TaskWorkerMap[victim.ID]; ok {
+		t.Error("the victim should no longer be assigned to the worker")
+	}
+	if n := m.WorkerNodes[0]; n.MemoryAllocated != 0 || n.TaskCount != 0 {
+		t.Errorf("node has %d bytes allocated to %d tasks, want the victim's resources released", n.MemoryAllocated, n.TaskCount)
+	}
+	te, ok := m.Pending.Dequeue()
+	if !ok || te.Task.ID != victim.ID {
+		t.Error("the victim should be pending again")
+	}
+}
+
+func TestPreemptKeepsVictimTheWorkerDidNotStop(t *testing.T) {
+	m, victim := newPreemptionManager(t, "binpack", http.StatusInternalServerError)
+
+	if m.preempt(task.Task{ID: uuid.New(), PriorityClass: "high", Memory: 500_000}) {
+		t.Fatal("preempt() should fail when the worker doesn't stop the victim")
+	}
+	if m.TaskWorkerMap[victim.ID] != m.Workers[0] {
+		t.Error("the victim should stay assigned to its worker")
+	}
+	if n := m.WorkerNodes[0]; n.MemoryAllocated != victim.Memory || n.TaskCount != 1 {
+		t.Errorf("node has %d bytes allocated to %d tasks, want the victim's resources kept", n.MemoryAllocated, n.TaskCount)
+	}
+	if taskState(m, victim.ID) != task.Running {
+		t.Errorf("victim is %s, want it still Running", taskState(m, victim.ID))
+	}
+	if m.Pending.Len() != 0 {
+		t.Errorf("pending queue has %d events, want none", m.Pending.Len())
+	}
+}
+
+func TestSendWorkPreemptsWithSchedulersNotFilteringOnResources(t *testing.T) {
+	m, victim := newPreemptionManager(t, "roundrobin", http.StatusNoContent)
+	preemptor := task.Task{ID: uuid.New(), Name: "web", PriorityClass: "high", Memory: 500_000}
+	m.AddTask(task.Event{ID: uuid.New(), State: task.Scheduled, Task: preemptor})
+
+	m.SendWork()
+
+	if _, ok := m.TaskWorkerMap[preemptor.ID]; ok {
+		t.Fatal("the task should not be sent to a full node")
+	}
+	if _, ok := m.TaskWorkerMap[victim.ID]; ok {
+		t.Error("the lower priority task should be preempted")
+	}
+	// the preemptor is requeued ahead of its victim
+	te, ok := m.Pending.Dequeue()
+	if !ok || te.Task.ID != preemptor.ID {
+		t.Error("the preempting task should be pending again")
+	}
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/service"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+// ApplyService creates the service or updates the replica count and template of an existing one.
+// Replicas already running keep the template they were created from.
+func (m *Manager) ApplyService(s service.Service) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+	svc := s
+	m.Services[s.Name] = &svc
+	log.Printf("[manager] applied service %s with %d replicas\n", s.Name, s.Replicas)
+	m.reconcileService(&svc)
+	return nil
+}
+
+func (m *Manager) GetServices() []service.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := []service.Status{}
+	for _, s := range m.Services {
+		statuses = append(statuses, m.serviceStatus(s))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (m *Manager) GetService(name string) (service.Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.Services[name]
+	if !ok {
+		return service.Status{}, fmt.Errorf("service %s not found", name)
+	}
+	return m.serviceStatus(s), nil
+}
+
+func (m *Manager) serviceStatus(s *service.Service) service.Status {
+	status := service.Status{Service: *s}
+	for _, t := range m.replicas(s.Name) {
+		status.Active++
+		if t.State == task.Running {
+			status.Running++
+		}
+	}
+	return status
+}
+
+// ScaleService sets the number of replicas of a service.
+func (m *Manager) ScaleService(name string, replicas int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scaleService(name, replicas)
+}
+
+func (m *Manager) scaleService(name string, replicas int) error {
+	s, ok := m.Services[name]
+	if !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+	if replicas < 0 {
+		return fmt.Errorf("replicas must not be negative")
+	}
+	if s.Replicas != replicas {
+		log.Printf("[manager] scaling service %s from %d to %d replicas\n", name, s.Replicas, replicas)
+	}
+	s.Replicas = replicas
+	m.reconcileService(s)
+	return nil
+}
+
+// DeleteService stops all replicas of a service and removes it along with its autoscalers.
+func (m *Manager) DeleteService(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.Services[name]
+	if !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+	s.Replicas = 0
+	m.reconcileService(s)
+	delete(m.Services, name)
+	for hpaName, h := range m.Autoscalers {
+		if h.Service == name {
+			delete(m.Autoscalers, hpaName)
+		}
+	}
+	log.Printf("[manager] deleted service %s\n", name)
+	return nil
+}
+
+// ReconcileServices periodically starts or stops replicas so that each service runs the number
+// of replicas it asks for. Failed replicas are replaced.
+func (m *Manager) ReconcileServices() {
+	for {
+		m.mu.Lock()
+		for _, s := range m.Services {
+			m.reconcileService(s)
+		}
+		m.mu.Unlock()
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func (m *Manager) reconcileService(s *service.Service) {
+	replicas := m.replicas(s.Name)
+	switch {
+	case len(replicas) < s.Replicas:
+		for i := len(replicas); i < s.Replicas; i++ {
+			m.createReplica(s)
+		}
+	case len(replicas) > s.Replicas:
+		// stop the replicas that are furthest from running first, then the newest ones
+		sort.SliceStable(replicas, func(i, j int) bool {
+			ri, rj := replicaRank(replicas[i]), replicaRank(replicas[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return replicas[i].StartTime.After(replicas[j].StartTime)
+		})
+		for _, t := range replicas[:len(replicas)-s.Replicas] {
+			m.stopReplica(t)
+		}
+	}
+}
+
+func replicaRank(t *task.Task) int {
+	switch t.State {
+	case task.Pending:
+		return 0
+	case task.Scheduled, task.Lost, task.Evicted:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// replicas returns the tasks of the service that are not finished and not being stopped.
+func (m *Manager) replicas(name string) []*task.Task {
+	var replicas []*task.Task
+	result, err := m.TaskDb.List()
+	if err != nil {
+		log.Printf("[manager] error listing tasks: %v\n", err)
+		return nil
+	}
+	for _, t := range result.([]*task.Task) {
+		if t.Service != name {
+			continue
+		}
+		if t.State == task.Completed || t.State == task.Failed {
+			delete(m.terminating, t.ID)
+			continue
+		}
+		if m.terminating[t.ID] {
+			continue
+		}
+		replicas = append(replicas, t)
+	}
+	return replicas
+}
+
+func (m *Manager) createReplica(s *service.Service) {
+	t := s.Template
+	t.ID = uuid.New()
+	t.Name = fmt.Sprintf("%s-%s", s.Name, t.ID.String()[:8])
+	t.Service = s.Name
+	t.State = task.Pending
+	t.ContainerID = ""
+	t.StartTime = time.Time{}
+	t.FinishTime = time.Time{}
+	t.RestartCount = 0
+
+	// store the replica right away, so that it is counted while it waits in the pending queue
+	m.TaskDb.Put(t.ID.String(), &t)
+
+	te := task.Event{
+		ID:        uuid.New(),
+		State:     task.Scheduled,
+		Timestamp: time.Now(),
+		Task:      t,
+	}
+	te.Task.State = task.Scheduled
+	m.Pending.Enqueue(te)
+	log.Printf("[manager] created replica %s of service %s\n", t.Name, s.Name)
+}
+
+func (m *Manager) stopReplica(t *task.Task) {
+	m.terminating[t.ID] = true
+	log.Printf("[manager] stopping replica %s of service %s\n", t.Name, t.Service)
+
+	if _, assigned := m.TaskWorkerMap[t.ID]; !assigned {
+		// the replica hasn't been placed yet, SendWork drops it when it comes off the queue
+		t.State = task.Completed
+		t.FinishTime = time.Now().UTC()
+		m.TaskDb.Put(t.ID.String(), t)
+		return
+	}
+
+	te := task.Event{
+		ID:        uuid.New(),
+		State:     task.Completed,
+		Timestamp: time.Now(),
+		Task:      *t,
+	}
+	m.Pending.Enqueue(te)
+}
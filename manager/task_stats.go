@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/google/uuid"
+)
+
+// CollectTaskStats periodically fetches the resource usage the workers collected for their
+// running tasks, with one request per worker.
+func (m *Manager) CollectTaskStats() {
+	for {
+		log.Println("Collecting task stats from workers")
+		m.collectTaskStats()
+		time.Sleep(15 * time.Second)
+	}
+}
+
+func (m *Manager) collectTaskStats() {
+	m.mu.Lock()
+	workers := append([]WorkerAddress{}, m.Workers...)
+	m.mu.Unlock()
+
+	reported := make(map[WorkerAddress][]*stats.TaskStats)
+	for _, w := range workers {
+		s, err := getTaskStats(w)
+		if err != nil {
+			log.Printf("[manager] error collecting task stats from worker %s: %v\n", w, err)
+			continue
+		}
+		reported[w] = s
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	collected := make(map[uuid.UUID]*stats.TaskStats)
+	for w, samples := range reported {
+		for _, s := range samples {
+			// leave out tasks the manager has moved elsewhere
+			if m.TaskWorkerMap[s.TaskID] == w {
+				collected[s.TaskID] = s
+			}
+		}
+	}
+	m.TaskStats = collected
+}
+
+// getTaskStats returns the latest stats sample of each running task of worker w.
+func getTaskStats(w WorkerAddress) ([]*stats.TaskStats, error) {
+	url := fmt.Sprintf("http://%s/tasks/stats", w)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %v", w, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("worker %s returned status %d", w, resp.StatusCode)
+	}
+
+	var s []*stats.TaskStats
+	err = json.NewDecoder(resp.Body).Decode(&s)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding stats: %v", err)
+	}
+	return s, nil
+}
+
+// GetTaskStats returns the latest resource usage collected for each running task.
+func (m *Manager) GetTaskStats() []*stats.TaskStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	taskStats := []*stats.TaskStats{}
+	for _, s := range m.TaskStats {
+		taskStats = append(taskStats, s)
+	}
+	return taskStats
+}
+
+// GetTaskStat returns the latest resource usage collected for the task, if any.
+func (m *Manager) GetTaskStat(id uuid.UUID) (*stats.TaskStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.TaskStats[id]
+	return s, ok
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// HTTPMetrics counts the requests served by a chi router and how long they took.
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+func NewHTTPMetrics(r *Registry, prefix string) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec(prefix+"_http_requests_total", "Number of HTTP requests served.", "method", "route", "code"),
+		duration: r.NewHistogramVec(prefix+"_http_request_duration_seconds", "Time taken to serve HTTP requests.", DefBuckets, "method", "route"),
+	}
+}
+
+// Middleware records every request under its route pattern, e.g. /tasks/{taskID}, rather than
+// the raw path, to keep the number of series bounded.
+func (h *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		h.requests.Inc(r.Method, route, strconv.Itoa(status))
+		h.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
@@ -0,0 +1,274 @@
+// Package metrics is a small implementation of Prometheus metrics: counters, gauges and histograms
+// with labels, exposed over HTTP in the text exposition format.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, suited to request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds the metrics of a component and renders them for scraping.
+type Registry struct {
+	mu        sync.Mutex
+	metrics   []metric
+	onCollect []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// OnCollect registers a function that is called before every scrape, to update gauges whose
+// values are read from elsewhere, such as queue lengths or node stats.
+func (r *Registry) OnCollect(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onCollect = append(r.onCollect, f)
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write renders all metrics in the text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.onCollect...)
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics, typically mounted at /metrics.
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(200)
+		r.Write(w)
+	}
+}
+
+// vec keeps one value per combination of label values.
+type vec struct {
+	mu         sync.Mutex
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	series     map[string][]string // [key]label values
+}
+
+func newVec(name string, help string, kind string, labelNames []string) vec {
+	return vec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		series:     make(map[string][]string),
+	}
+}
+
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	k := strings.Join(labelValues, "\xff")
+	if _, ok := v.series[k]; !ok {
+		v.series[k] = append([]string{}, labelValues...)
+	}
+	return k
+}
+
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	// helpEscaper escapes HELP text, in which only backslashes and line feeds are special.
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	// labelValueEscaper escapes label values, in which double quotes are special as well.
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func (v *vec) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, helpEscaper.Replace(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+func (v *vec) labels(labelValues []string, extra ...string) string {
+	var pairs []string
+	for i, name := range v.labelNames {
+		pairs = append(pairs, labelPair(name, labelValues[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, labelPair(extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func labelPair(name string, value string) string {
+	return name + `="` + labelValueEscaper.Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// CounterVec is a value that only goes up, such as the number of requests served.
+type CounterVec struct {
+	vec
+	values map[string]float64
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labelNames), values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.name))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[c.key(labelValues)] += delta
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w)
+	for _, k := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labels(c.series[k]), formatFloat(c.values[k]))
+	}
+}
+
+// GaugeVec is a value that can go up and down, such as a queue length.
+type GaugeVec struct {
+	vec
+	values map[string]float64
+}
+
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labelNames), values: make(map[string]float64)}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[g.key(labelValues)] = value
+}
+
+// Reset drops all series, so that label combinations that no longer exist, like removed nodes,
+// stop being reported.
+func (g *GaugeVec) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values = make(map[string]float64)
+	g.series = make(map[string][]string)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeader(w)
+	for _, k := range g.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labels(g.series[k]), formatFloat(g.values[k]))
+	}
+}
+
+// HistogramVec counts observations, such as request durations, in cumulative buckets.
+type HistogramVec struct {
+	vec
+	buckets []float64
+	counts  map[string][]uint64
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{
+		vec:     newVec(name, help, "histogram", labelNames),
+		buckets: buckets,
+		counts:  make(map[string][]uint64),
+		sums:    make(map[string]float64),
+		totals:  make(map[string]uint64),
+	}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := h.key(labelValues)
+	if _, ok := h.counts[k]; !ok {
+		h.counts[k] = make([]uint64, len(h.buckets))
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.counts[k][i]++
+		}
+	}
+	h.sums[k] += value
+	h.totals[k]++
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	for _, k := range h.sortedKeys() {
+		lv := h.series[k]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(lv, "le", formatFloat(upper)), h.counts[k][i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(lv, "le", "+Inf"), h.totals[k])
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labels(lv), formatFloat(h.sums[k]))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels(lv), h.totals[k])
+	}
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLabelPair(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "worker-1", `node="worker-1"`},
+		{"empty", "", `node=""`},
+		{"double quote", `say "hi"`, `node="say \"hi\""`},
+		{"backslash", `C:\tmp`, `node="C:\\tmp"`},
+		{"line feed", "a\nb", `node="a\nb"`},
+		{"escaped line feed", `a\nb`, `node="a\\nb"`},
+		{"tab is kept", "a\tb", "node=\"a\tb\""},
+		{"unicode is kept", "nœud", `node="nœud"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := labelPair("node", tt.value)
+			if got != tt.want {
+				t.Errorf("labelPair(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteHeaderEscapesHelp(t *testing.T) {
+	tests := []struct {
+		name string
+		help string
+		want string
+	}{
+		{"plain", "Number of tasks.", "# HELP cube_tasks Number of tasks.\n"},
+		{"double quote is kept", `Tasks in "Running".`, "# HELP cube_tasks Tasks in \"Running\".\n"},
+		{"backslash", `Paths like C:\tmp.`, "# HELP cube_tasks Paths like C:\\\\tmp.\n"},
+		{"line feed", "Number\nof tasks.", "# HELP cube_tasks Number\\nof tasks.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVec("cube_tasks", tt.help, "gauge", nil)
+			var b strings.Builder
+			v.writeHeader(&b)
+			got := strings.SplitAfter(b.String(), "\n")[0]
+			if got != tt.want {
+				t.Errorf("HELP line = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.in); got != tt.want {
+			t.Errorf("formatFloat(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(r *Registry)
+		want   string
+	}{
+		{
+			name: "counter",
+			record: func(r *Registry) {
+				c := r.NewCounterVec("cube_requests_total", "Requests served.", "code")
+				c.Inc("500")
+				c.Add(2, "200")
+			},
+			want: `# HELP cube_requests_total Requests served.
+# TYPE cube_requests_total counter
+cube_requests_total{code="200"} 2
+cube_requests_total{code="500"} 1
+`,
+		},
+		{
+			name: "gauge without labels",
+			record: func(r *Registry) {
+				r.NewGaugeVec("cube_pending_tasks", "Pending tasks.").Set(3)
+			},
+			want: `# HELP cube_pending_tasks Pending tasks.
+# TYPE cube_pending_tasks gauge
+cube_pending_tasks 3
+`,
+		},
+		{
+			name: "gauge with escaped label values",
+			record: func(r *Registry) {
+				r.NewGaugeVec("cube_task_memory_bytes", "Memory.", "task_name").Set(1024, "web \"a\"\\\n")
+			},
+			want: `# HELP cube_task_memory_bytes Memory.
+# TYPE cube_task_memory_bytes gauge
+cube_task_memory_bytes{task_name="web \"a\"\\\n"} 1024
+`,
+		},
+		{
+			name: "gauge reset",
+			record: func(r *Registry) {
+				g := r.NewGaugeVec("cube_node_tasks", "Tasks.", "node")
+				g.Set(1, "worker-1")
+				g.Reset()
+				g.Set(2, "worker-2")
+			},
+			want: `# HELP cube_node_tasks Tasks.
+# TYPE cube_node_tasks gauge
+cube_node_tasks{node="worker-2"} 2
+`,
+		},
+		{
+			name: "histogram",
+			record: func(r *Registry) {
+				h := r.NewHistogramVec("cube_duration_seconds", "Duration.", []float64{0.1, 1}, "route")
+				h.Observe(0.05, "/tasks")
+				h.Observe(0.5, "/tasks")
+				h.Observe(5, "/tasks")
+			},
+			want: `# HELP cube_duration_seconds Duration.
+# TYPE cube_duration_seconds histogram
+cube_duration_seconds_bucket{route="/tasks",le="0.1"} 1
+cube_duration_seconds_bucket{route="/tasks",le="1"} 2
+cube_duration_seconds_bucket{route="/tasks",le="+Inf"} 3
+cube_duration_seconds_sum{route="/tasks"} 5.55
+cube_duration_seconds_count{route="/tasks"} 3
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.record(r)
+			var b strings.Builder
+			r.Write(&b)
+			if got := b.String(); got != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRunsCollectHooks(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("cube_queue_depth", "Queue depth.")
+	depth := 0
+	r.OnCollect(func() {
+		depth++
+		g.Set(float64(depth))
+	})
+
+	var b strings.Builder
+	r.Write(&b)
+	b.Reset()
+	r.Write(&b)
+	if !strings.Contains(b.String(), "cube_queue_depth 2\n") {
+		t.Errorf("expected the hook to update the gauge before each write, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import "github.com/ahmadateya/my-own-k8s/stats"
+
+// StatsGauges exposes the host level stats.Stats of a node.
+type StatsGauges struct {
+	memoryTotal     *GaugeVec
+	memoryAvailable *GaugeVec
+	diskTotal       *GaugeVec
+	diskFree        *GaugeVec
+	cpuUsage        *GaugeVec
+	load1           *GaugeVec
+	load5           *GaugeVec
+	load15          *GaugeVec
+	cores           *GaugeVec
+	mountTotal      *GaugeVec
+	mountFree       *GaugeVec
+	netRx           *GaugeVec
+	netTx           *GaugeVec
+	pressure        *GaugeVec
+}
+
+func NewStatsGauges(r *Registry, prefix string, labelNames ...string) *StatsGauges {
+	with := func(extra ...string) []string {
+		return append(append([]string{}, labelNames...), extra...)
+	}
+	return &StatsGauges{
+		memoryTotal:     r.NewGaugeVec(prefix+"_memory_total_bytes", "Total memory of the host.", labelNames...),
+		memoryAvailable: r.NewGaugeVec(prefix+"_memory_available_bytes", "Memory available for starting new applications.", labelNames...),
+		diskTotal:       r.NewGaugeVec(prefix+"_disk_total_bytes", "Size of the root filesystem.", labelNames...),
+		diskFree:        r.NewGaugeVec(prefix+"_disk_free_bytes", "Free space on the root filesystem.", labelNames...),
+		cpuUsage:        r.NewGaugeVec(prefix+"_cpu_usage_ratio", "Share of CPU time spent non-idle since boot.", labelNames...),
+		load1:           r.NewGaugeVec(prefix+"_load1", "1 minute load average.", labelNames...),
+		load5:           r.NewGaugeVec(prefix+"_load5", "5 minute load average.", labelNames...),
+		load15:          r.NewGaugeVec(prefix+"_load15", "15 minute load average.", labelNames...),
+		cores:           r.NewGaugeVec(prefix+"_cpu_cores", "Number of CPU cores.", labelNames...),
+		mountTotal:      r.NewGaugeVec(prefix+"_mount_total_bytes", "Size of the filesystem holding a monitored path.", with("path")...),
+		mountFree:       r.NewGaugeVec(prefix+"_mount_free_bytes", "Free space on the filesystem holding a monitored path.", with("path")...),
+		netRx:           r.NewGaugeVec(prefix+"_network_receive_bytes", "Bytes received on an interface since boot.", with("interface")...),
+		netTx:           r.NewGaugeVec(prefix+"_network_transmit_bytes", "Bytes sent on an interface since boot.", with("interface")...),
+		pressure:        r.NewGaugeVec(prefix+"_pressure_ratio", "Share of the last 10 seconds some tasks were stalled on a resource.", with("resource")...),
+	}
+}
+
+// Set updates the gauges from s. Stats that have not been collected are skipped.
+func (g *StatsGauges) Set(s *stats.Stats, labelValues ...string) {
+	if s == nil {
+		return
+	}
+	if s.MemStats != nil {
+		// /proc/meminfo reports kibibytes
+		g.memoryTotal.Set(float64(s.MemTotalKb())*1024, labelValues...)
+		g.memoryAvailable.Set(float64(s.MemAvailableKb())*1024, labelValues...)
+	}
+	if s.DiskStats != nil {
+		g.diskTotal.Set(float64(s.DiskTotal()), labelValues...)
+		g.diskFree.Set(float64(s.DiskFree()), labelValues...)
+	}
+	if s.CpuStats != nil {
+		g.cpuUsage.Set(s.CpuUsage(), labelValues...)
+	}
+	if s.LoadStats != nil {
+		g.load1.Set(s.LoadStats.Last1Min, labelValues...)
+		g.load5.Set(s.LoadStats.Last5Min, labelValues...)
+		g.load15.Set(s.LoadStats.Last15Min, labelValues...)
+	}
+	if cores := s.Cores(); cores > 0 {
+		g.cores.Set(float64(cores), labelValues...)
+	}
+	for path, d := range s.MountStats {
+		g.mountTotal.Set(float64(d.All), append(labelValues, path)...)
+		g.mountFree.Set(float64(d.Free), append(labelValues, path)...)
+	}
+	for _, n := range s.NetStats {
+		g.netRx.Set(float64(n.RxBytes), append(labelValues, n.Iface)...)
+		g.netTx.Set(float64(n.TxBytes), append(labelValues, n.Iface)...)
+	}
+	if p := s.PressureStats; p != nil {
+		for resource, ps := range map[string]*stats.PressureStat{"cpu": p.Cpu, "memory": p.Memory, "io": p.Io} {
+			if ps != nil {
+				g.pressure.Set(ps.Some.Avg10/100, append(labelValues, resource)...)
+			}
+		}
+	}
+}
+
+func (g *StatsGauges) Reset() {
+	for _, v := range []*GaugeVec{g.memoryTotal, g.memoryAvailable, g.diskTotal, g.diskFree, g.cpuUsage, g.load1, g.load5, g.load15, g.cores, g.mountTotal, g.mountFree, g.netRx, g.netTx, g.pressure} {
+		v.Reset()
+	}
+}
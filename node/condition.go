@@ -0,0 +1,29 @@
+package node
+
+import "time"
+
+type Condition string
+
+const (
+	// Ready means the manager has heard from the node recently and it answered without errors.
+	Ready Condition = "Ready"
+	// NotReady means the node answered the manager but reported an error.
+	NotReady Condition = "NotReady"
+	// Unknown means the manager has not heard from the node within the grace period.
+	Unknown Condition = "Unknown"
+)
+
+// SetCondition updates the node's condition, recording the time of the transition if it changed.
+func (n *Node) SetCondition(c Condition) {
+	if n.Condition == c {
+		return
+	}
+	n.Condition = c
+	n.LastTransition = time.Now()
+}
+
+// MarkContact records a successful poll or heartbeat from the node, making it Ready.
+func (n *Node) MarkContact() {
+	n.LastContact = time.Now()
+	n.SetCondition(Ready)
+}
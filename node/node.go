@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 )
 
 type Node struct {
@@ -22,17 +23,51 @@ type Node struct {
 	DiskAllocated   uint64
 	Role            string
 	TaskCount       uint64
+	Taints          []Taint
+	Unschedulable   bool // set when the node is cordoned, no new tasks are placed on it
+	Labels          map[string]string
+	LastHeartbeat   time.Time
+	Condition       Condition
+	LastTransition  time.Time           // when Condition last changed
+	LastContact     time.Time           // last successful poll or heartbeat
+	Pressure        []PressureCondition // resources the worker is evicting tasks to reclaim
+
+	// trend is the worker's stats time series over the last TrendWindow, oldest first.
+	trend []stats.Point
 }
 
+// ZoneLabel is the node label used to group nodes into failure domains.
+const ZoneLabel = "zone"
+
+// TrendWindow is how far back the stats time series fetched from a worker goes.
+const TrendWindow = 5 * time.Minute
+
 func New(name string, api string, role string) *Node {
+	// nodes start out Ready, the manager marks them otherwise once it fails to reach them
+	now := time.Now()
 	return &Node{
-		Name: name,
-		Api:  api,
-		Role: role,
+		Name:           name,
+		Api:            api,
+		Role:           role,
+		Condition:      Ready,
+		LastTransition: now,
+		LastContact:    now,
 	}
 }
 
 func (n *Node) GetStats() (*stats.Stats, error) {
+	s, trend, err := n.FetchStats()
+	if err != nil {
+		return nil, err
+	}
+	n.SetStats(*s, trend)
+	return &n.Stats, nil
+}
+
+// FetchStats gets the node's current stats and its stats trend from the worker without recording
+// them on the node, so it can be called without holding the lock guarding the node. The trend is
+// nil if it couldn't be fetched.
+func (n *Node) FetchStats() (*stats.Stats, []stats.Point, error) {
 	var resp *http.Response
 	var err error
 
@@ -41,13 +76,13 @@ func (n *Node) GetStats() (*stats.Stats, error) {
 	if err != nil {
 		msg := fmt.Sprintf("Unable to connect to %v. Permanent failure.\n", n.Api)
 		log.Println(msg)
-		return nil, fmt.Errorf(msg)
+		return nil, nil, fmt.Errorf(msg)
 	}
 
 	if resp.StatusCode != 200 {
 		msg := fmt.Sprintf("Error retrieving stats from %v: %v", n.Api, err)
 		log.Println(msg)
-		return nil, fmt.Errorf(msg)
+		return nil, nil, fmt.Errorf(msg)
 	}
 
 	defer resp.Body.Close()
@@ -57,16 +92,88 @@ func (n *Node) GetStats() (*stats.Stats, error) {
 	if err != nil {
 		msg := fmt.Sprintf("error decoding message while getting stats for node %s", n.Name)
 		log.Println(msg)
-		return nil, fmt.Errorf(msg)
+		return nil, nil, fmt.Errorf(msg)
 	}
 
 	if stats.MemStats == nil || stats.DiskStats == nil {
-		return nil, fmt.Errorf("error getting stats from node %s", n.Name)
+		return nil, nil, fmt.Errorf("error getting stats from node %s", n.Name)
+	}
+
+	trend, err := n.getTrend()
+	if err != nil {
+		log.Printf("error getting stats trend for node %s: %v", n.Name, err)
 	}
+	return &stats, trend, nil
+}
 
-	n.Memory = stats.MemTotalKb()
-	n.Disk = stats.DiskTotal()
-	n.Stats = stats
+// SetStats records stats fetched from the worker on the node. The node's capacity is only taken
+// from the stats where the worker didn't declare it when registering. A nil trend keeps the
+// previous one.
+func (n *Node) SetStats(s stats.Stats, trend []stats.Point) {
+	if n.Memory == 0 {
+		n.Memory = s.MemTotalKb()
+	}
+	if n.Disk == 0 {
+		n.Disk = s.DiskTotal()
+	}
+	if n.Cores == 0 {
+		n.Cores = s.Cores()
+	}
+	n.Stats = s
+	if trend != nil {
+		n.trend = trend
+	}
+}
 
-	return &n.Stats, nil
+// Copy returns a copy of the node that shares no labels, taints or pressure conditions with it.
+func (n *Node) Copy() *Node {
+	c := *n
+	c.Taints = append([]Taint(nil), n.Taints...)
+	c.Pressure = append([]PressureCondition(nil), n.Pressure...)
+	if n.Labels != nil {
+		c.Labels = make(map[string]string, len(n.Labels))
+		for k, v := range n.Labels {
+			c.Labels[k] = v
+		}
+	}
+	return &c
+}
+
+func (n *Node) getTrend() ([]stats.Point, error) {
+	url := fmt.Sprintf("%s/stats?since=%s", n.Api, TrendWindow)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("worker returned status %d", resp.StatusCode)
+	}
+
+	var trend []stats.Point
+	err = json.NewDecoder(resp.Body).Decode(&trend)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding stats series: %v", err)
+	}
+	return trend, nil
+}
+
+// Trend returns the node's stats time series over the last TrendWindow.
+func (n *Node) Trend() []stats.Point {
+	return n.trend
+}
+
+// CpuUsage returns the node's average CPU usage over the last TrendWindow. It does not contact
+// the node, so it is cheap enough to call while scheduling.
+func (n *Node) CpuUsage() (float64, error) {
+	// the first point may lack a previous sample to compute its usage against
+	if len(n.trend) < 2 {
+		return 0, fmt.Errorf("not enough stats samples for node %s", n.Name)
+	}
+	var total float64
+	for _, p := range n.trend[1:] {
+		total += p.CpuPercent
+	}
+	return total / float64(len(n.trend)-1) / 100, nil
 }
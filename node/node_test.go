@@ -0,0 +1,45 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/c9s/goprocinfo/linux"
+)
+
+func TestSetStatsKeepsDeclaredCapacity(t *testing.T) {
+	s := stats.Stats{
+		MemStats:     &linux.MemInfo{MemTotal: 16 << 20},
+		DiskStats:    &linux.Disk{All: 500 << 30},
+		CpuCoreStats: make([]linux.CPUStat, 8),
+	}
+
+	declared := New("worker-1", "http://worker-1", "worker")
+	declared.Memory = 4 << 20
+	declared.Disk = 100 << 30
+	declared.Cores = 2
+	declared.SetStats(s, nil)
+	if declared.Memory != 4<<20 || declared.Disk != 100<<30 || declared.Cores != 2 {
+		t.Errorf("capacity = %d KiB, %d bytes, %d cores, want the declared capacity kept", declared.Memory, declared.Disk, declared.Cores)
+	}
+	if declared.Stats.MemStats == nil {
+		t.Error("SetStats() should record the stats")
+	}
+
+	undeclared := New("worker-2", "http://worker-2", "worker")
+	undeclared.SetStats(s, nil)
+	if undeclared.Memory != 16<<20 || undeclared.Disk != 500<<30 || undeclared.Cores != 8 {
+		t.Errorf("capacity = %d KiB, %d bytes, %d cores, want it taken from the stats", undeclared.Memory, undeclared.Disk, undeclared.Cores)
+	}
+}
+
+func TestSetStatsKeepsTrendWhenNotFetched(t *testing.T) {
+	n := New("worker-1", "http://worker-1", "worker")
+	s := stats.Stats{MemStats: &linux.MemInfo{}, DiskStats: &linux.Disk{}}
+
+	n.SetStats(s, []stats.Point{{CpuPercent: 50}})
+	n.SetStats(s, nil)
+	if len(n.trend) != 1 || n.trend[0].CpuPercent != 50 {
+		t.Errorf("trend = %v, want the one fetched before", n.trend)
+	}
+}
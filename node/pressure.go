@@ -0,0 +1,37 @@
+package node
+
+// PressureCondition is reported by a worker that is running low on a resource and evicting tasks
+// to reclaim it.
+type PressureCondition string
+
+const (
+	MemoryPressure PressureCondition = "MemoryPressure"
+	DiskPressure   PressureCondition = "DiskPressure"
+)
+
+var pressureTaintKeys = map[PressureCondition]string{
+	MemoryPressure: "cube/memory-pressure",
+	DiskPressure:   "cube/disk-pressure",
+}
+
+// SetPressure records the pressure conditions reported by the node and keeps a NoSchedule taint
+// for each of them, so that no new tasks are placed on it until the pressure is relieved.
+func (n *Node) SetPressure(conditions []PressureCondition) {
+	n.Pressure = conditions
+	for c, key := range pressureTaintKeys {
+		if containsPressure(conditions, c) {
+			n.AddTaint(Taint{Key: key, Effect: NoSchedule})
+		} else {
+			n.RemoveTaint(key)
+		}
+	}
+}
+
+func containsPressure(conditions []PressureCondition, c PressureCondition) bool {
+	for _, pc := range conditions {
+		if pc == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package node
+
+import (
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// WorkerNameLabel is the label holding the name a worker registered with.
+const WorkerNameLabel = "worker"
+
+// Registration is sent by a worker to the manager when it starts, announcing the address the
+// manager can reach it on and its capacity.
+type Registration struct {
+	Name    string
+	Address string // <hostname>:<port>
+	Memory  uint64
+	Disk    uint64
+	Cores   int
+	Labels  map[string]string
+}
+
+// Heartbeat is sent periodically by a registered worker to tell the manager it is still alive.
+type Heartbeat struct {
+	Address   string
+	TaskCount int
+	Pressure  []PressureCondition
+	Timestamp time.Time
+}
+
+// TaskStatusReport is pushed by a worker when the status of its tasks changes. The manager keeps
+// the task with the highest ResourceVersion, so reports may arrive out of order.
+type TaskStatusReport struct {
+	Address string
+	Tasks   []task.Task
+}
@@ -0,0 +1,57 @@
+package node
+
+import "fmt"
+
+type TaintEffect string
+
+const (
+	// NoSchedule prevents new tasks from being placed on the node unless they tolerate the taint.
+	NoSchedule TaintEffect = "NoSchedule"
+	// PreferNoSchedule makes the scheduler avoid the node, but it may still be picked as a last resort.
+	PreferNoSchedule TaintEffect = "PreferNoSchedule"
+	// NoExecute behaves like NoSchedule and also evicts running tasks that do not tolerate the taint.
+	NoExecute TaintEffect = "NoExecute"
+)
+
+// Taint marks a node so that only tasks with a matching toleration are placed on it.
+type Taint struct {
+	Key    string
+	Value  string
+	Effect TaintEffect
+}
+
+func (t Taint) Validate() error {
+	if t.Key == "" {
+		return fmt.Errorf("taint key must not be empty")
+	}
+	switch t.Effect {
+	case NoSchedule, PreferNoSchedule, NoExecute:
+		return nil
+	default:
+		return fmt.Errorf("invalid taint effect %q", t.Effect)
+	}
+}
+
+// AddTaint adds the taint to the node, replacing an existing taint with the same key and effect.
+func (n *Node) AddTaint(taint Taint) {
+	for i := range n.Taints {
+		if n.Taints[i].Key == taint.Key && n.Taints[i].Effect == taint.Effect {
+			n.Taints[i] = taint
+			return
+		}
+	}
+	n.Taints = append(n.Taints, taint)
+}
+
+// RemoveTaint removes every taint with the given key and reports whether any was found.
+func (n *Node) RemoveTaint(key string) bool {
+	var taints []Taint
+	for _, t := range n.Taints {
+		if t.Key != key {
+			taints = append(taints, t)
+		}
+	}
+	removed := len(taints) != len(n.Taints)
+	n.Taints = taints
+	return removed
+}
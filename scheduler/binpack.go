@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// MostAllocated is a bin-packing scheduler: it prefers the nodes that would be the most allocated
+// after placing the task, consolidating tasks onto fewer nodes so whole workers can be freed.
+type MostAllocated struct {
+	Name string
+}
+
+func (b *MostAllocated) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	return selectFittingNodes(t, nodes)
+}
+
+func (b *MostAllocated) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	nodeScores := make(map[string]float64)
+	for _, n := range nodes {
+		nodeScores[n.Name] = 1 - allocatedFraction(t, n) + taintPenalty(t, n)
+	}
+	return nodeScores
+}
+
+func (b *MostAllocated) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return pickLowest(scores, candidates)
+}
+
+// selectFittingNodes keeps the schedulable nodes with enough unallocated disk and memory for the task
+// and whose taints the task tolerates.
+func selectFittingNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	var candidates []*node.Node
+	for _, n := range nodes {
+		if !checkSchedulable(n) {
+			continue
+		}
+		if n.DiskAllocated > n.Disk || !checkDisk(t, n.Disk-n.DiskAllocated) {
+			continue
+		}
+		if !checkMemory(t, n) || !checkTaints(t, n) {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	return candidates
+}
+
+// checkMemory compares the task's memory request with the node's unallocated memory.
+// Nodes whose memory capacity has not been collected yet are assumed to fit.
+func checkMemory(t task.Task, n *node.Node) bool {
+	if n.Memory == 0 {
+		return true
+	}
+	return (n.MemoryAllocated+t.Memory)/1000 <= n.Memory
+}
+
+// allocatedFraction is the average share of the node's memory and disk that would be allocated
+// once the task is placed on it.
+func allocatedFraction(t task.Task, n *node.Node) float64 {
+	var fractions []float64
+	if n.Memory > 0 {
+		fractions = append(fractions, calculateLoad(float64(n.MemoryAllocated+t.Memory)/1000, float64(n.Memory)))
+	}
+	if n.Disk > 0 {
+		fractions = append(fractions, calculateLoad(float64(n.DiskAllocated+t.Disk), float64(n.Disk)))
+	}
+	if len(fractions) == 0 {
+		return 0.00
+	}
+
+	sum := 0.0
+	for _, f := range fractions {
+		sum += f
+	}
+	return sum / float64(len(fractions))
+}
+
+func pickLowest(scores map[string]float64, candidates []*node.Node) *node.Node {
+	var bestNode *node.Node
+	var lowestScore float64
+	for idx, n := range candidates {
+		if idx == 0 || scores[n.Name] < lowestScore {
+			bestNode = n
+			lowestScore = scores[n.Name]
+		}
+	}
+	return bestNode
+}
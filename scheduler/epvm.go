@@ -4,7 +4,6 @@ import (
 	"github.com/ahmadateya/my-own-k8s/node"
 	"github.com/ahmadateya/my-own-k8s/task"
 	"math"
-	"time"
 )
 
 const (
@@ -20,7 +19,7 @@ type Epvm struct {
 func (e *Epvm) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
 	var candidates []*node.Node
 	for i := range nodes {
-		if checkDisk(t, nodes[i].Disk-nodes[i].DiskAllocated) {
+		if checkSchedulable(nodes[i]) && checkDisk(t, nodes[i].Disk-nodes[i].DiskAllocated) && checkTaints(t, nodes[i]) {
 			candidates = append(candidates, nodes[i])
 		}
 	}
@@ -33,10 +32,13 @@ func (e *Epvm) Score(t task.Task, nodes []*node.Node) map[string]float64 {
 	maxJobs := 4.0
 
 	for _, node := range nodes {
-		cpuUsage, _ := calculateCpuUsage(node)
-		cpuLoad := calculateLoad(*cpuUsage, math.Pow(2, 0.8))
+		cpuUsage := calculateCpuUsage(node)
+		cpuLoad := calculateLoad(cpuUsage, math.Pow(2, 0.8))
 
-		memoryAllocated := float64(node.Stats.MemUsedKb()) + float64(node.MemoryAllocated)
+		memoryAllocated := float64(node.MemoryAllocated)
+		if node.Stats.MemStats != nil {
+			memoryAllocated += float64(node.Stats.MemUsedKb())
+		}
 		memoryPercentAllocated := memoryAllocated / float64(node.Memory)
 
 		newMemPercent := calculateLoad(memoryAllocated+float64(t.Memory/1000), float64(node.Memory))
@@ -49,7 +51,7 @@ func (e *Epvm) Score(t task.Task, nodes []*node.Node) map[string]float64 {
 			math.Pow(LIEB, cpuLoad) -
 			math.Pow(LIEB, float64(node.TaskCount)/maxJobs)
 
-		nodeScores[node.Name] = memCost + cpuCost
+		nodeScores[node.Name] = memCost + cpuCost + taintPenalty(t, node)
 	}
 	return nodeScores
 }
@@ -93,38 +95,16 @@ func calculateLoad(usage float64, capacity float64) float64 {
 	return usage / capacity
 }
 
-// See discussion from this StackOverflow thread:
-// https://stackoverflow.com/questions/23367857/accurate-calculation-of-cpu-usage-given-in-percentage-in-linux
-func calculateCpuUsage(node *node.Node) (*float64, error) {
-	//stat1 := getNodeStats(node)
-	stat1, err := node.GetStats()
-	if err != nil {
-		return nil, err
-	}
-	time.Sleep(3 * time.Second)
-	//stat2 := getNodeStats(node)
-	stat2, err := node.GetStats()
-	if err != nil {
-		return nil, err
+// calculateCpuUsage uses the stats the manager has already collected for the node instead of
+// sampling it here, so scoring never blocks on the network. Until the node's trend has enough
+// samples it falls back to the average usage since boot from the latest sample.
+func calculateCpuUsage(n *node.Node) float64 {
+	usage, err := n.CpuUsage()
+	if err == nil {
+		return usage
 	}
-
-	stat1Idle := stat1.CpuStats.Idle + stat1.CpuStats.IOWait
-	stat2Idle := stat2.CpuStats.Idle + stat2.CpuStats.IOWait
-
-	stat1NonIdle := stat1.CpuStats.User + stat1.CpuStats.Nice + stat1.CpuStats.System + stat1.CpuStats.IRQ + stat1.CpuStats.SoftIRQ + stat1.CpuStats.Steal
-	stat2NonIdle := stat2.CpuStats.User + stat2.CpuStats.Nice + stat2.CpuStats.System + stat2.CpuStats.IRQ + stat2.CpuStats.SoftIRQ + stat2.CpuStats.Steal
-
-	stat1Total := stat1Idle + stat1NonIdle
-	stat2Total := stat2Idle + stat2NonIdle
-
-	total := stat2Total - stat1Total
-	idle := stat2Idle - stat1Idle
-
-	var cpuPercentUsage float64
-	if total == 0 && idle == 0 {
-		cpuPercentUsage = 0.00
-	} else {
-		cpuPercentUsage = (float64(total) - float64(idle)) / float64(total)
+	if n.Stats.CpuStats == nil {
+		return 0.00
 	}
-	return &cpuPercentUsage, nil
+	return n.Stats.CpuUsage()
 }
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/google/uuid"
+)
+
+// NodeVerdict is the outcome of scheduling a task against a single node.
+type NodeVerdict struct {
+	Node     string
+	Feasible bool   // whether the node passed SelectCandidateNodes
+	Reason   string // why the node was filtered out, empty when feasible
+	Score    float64
+}
+
+// Explanation describes how a scheduler would place a task without actually placing it.
+type Explanation struct {
+	TaskID   uuid.UUID
+	Nodes    []NodeVerdict
+	Selected string // empty when no node is feasible
+	Error    string // why no node was picked although some were feasible, e.g. a failed extender
+}
+
+// Explain runs the scheduler's filter, score and pick phases for t against copies of the nodes
+// and of the scheduler itself, so neither the nodes nor the scheduler state are modified.
+func Explain(s Scheduler, t task.Task, nodes []*node.Node) Explanation {
+	s = clone(s)
+	var copies []*node.Node
+	for _, n := range nodes {
+		c := *n
+		copies = append(copies, &c)
+	}
+
+	ex := Explanation{TaskID: t.ID}
+	feasible := make(map[string]bool)
+	for _, n := range s.SelectCandidateNodes(t, copies) {
+		feasible[n.Name] = true
+	}
+
+	var candidates []*node.Node
+	for _, n := range copies {
+		v := NodeVerdict{Node: n.Name, Feasible: feasible[n.Name]}
+		if v.Feasible {
+			candidates = append(candidates, n)
+		} else {
+			v.Reason = filterReason(t, n)
+		}
+		ex.Nodes = append(ex.Nodes, v)
+	}
+
+	if len(candidates) == 0 {
+		return ex
+	}
+
+	scores, err := Score(s, t, candidates)
+	for i := range ex.Nodes {
+		if ex.Nodes[i].Feasible {
+			ex.Nodes[i].Score = scores[ex.Nodes[i].Node]
+		}
+	}
+	if err != nil {
+		ex.Error = err.Error()
+		return ex
+	}
+	if picked := s.Pick(scores, candidates); picked != nil {
+		ex.Selected = picked.Name
+	}
+	return ex
+}
+
+// filterReason makes a best effort at naming the check a node failed.
+func filterReason(t task.Task, n *node.Node) string {
+	if !checkSchedulable(n) {
+		return "node is cordoned"
+	}
+	for _, taint := range n.Taints {
+		if taint.Effect != node.PreferNoSchedule && !ToleratesTaint(t, taint) {
+			return fmt.Sprintf("untolerated taint %s=%s:%s", taint.Key, taint.Value, taint.Effect)
+		}
+	}
+	if n.DiskAllocated > n.Disk || !checkDisk(t, n.Disk-n.DiskAllocated) {
+		return fmt.Sprintf("insufficient disk: requested %d, available %d", t.Disk, n.Disk-min(n.Disk, n.DiskAllocated))
+	}
+	if !checkMemory(t, n) {
+		return fmt.Sprintf("insufficient memory: requested %d, allocated %d of %d", t.Memory, n.MemoryAllocated, n.Memory)
+	}
+	return "rejected by scheduler"
+}
+
+// clone returns a copy of schedulers that keep state between calls, such as the round robin position.
+func clone(s Scheduler) Scheduler {
+	switch sc := s.(type) {
+	case *RoundRobin:
+		c := *sc
+		return &c
+	case *Epvm:
+		c := *sc
+		return &c
+	case *Extender:
+		c := *sc
+		c.Scheduler = clone(sc.Scheduler)
+		return &c
+	default:
+		return s
+	}
+}
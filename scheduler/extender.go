@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// ExtenderArgs is the body sent to both extender endpoints.
+type ExtenderArgs struct {
+	Task  task.Task
+	Nodes []*node.Node
+}
+
+// ExtenderFilterResult is the response of the extender's /filter endpoint.
+type ExtenderFilterResult struct {
+	Nodes       []string          // names of the nodes that passed the filter
+	FailedNodes map[string]string // [nodeName]reason
+	Error       string
+}
+
+// ExtenderScore is one entry of the response of the extender's /prioritize endpoint. Like the
+// built-in schedulers, a lower score is better.
+type ExtenderScore struct {
+	Node  string
+	Score float64
+}
+
+// Extender wraps a built-in scheduler and lets an external HTTP service narrow down the
+// candidate nodes and adjust their scores, so placement logic can be customized without
+// forking. The service must implement POST <URL>/filter and POST <URL>/prioritize.
+//
+// When the service can't be reached, times out or returns an error, a fail-open extender
+// falls back to the built-in decision while a fail-closed one refuses to place the task.
+type Extender struct {
+	Name      string
+	Scheduler Scheduler
+	URL       string
+	Timeout   time.Duration
+	FailOpen  bool
+	Weight    float64 // multiplier applied to the extender's scores before adding them to the built-in ones
+
+	client *http.Client
+}
+
+func NewExtender(s Scheduler, url string, timeout time.Duration, failOpen bool, weight float64) *Extender {
+	return &Extender{
+		Name:      "extender",
+		Scheduler: s,
+		URL:       url,
+		Timeout:   timeout,
+		FailOpen:  failOpen,
+		Weight:    weight,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *Extender) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	candidates := e.Scheduler.SelectCandidateNodes(t, nodes)
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	var result ExtenderFilterResult
+	err := e.call("filter", ExtenderArgs{Task: t, Nodes: candidates}, &result)
+	if err == nil && result.Error != "" {
+		err = fmt.Errorf("extender filter error: %s", result.Error)
+	}
+	if err != nil {
+		log.Printf("[scheduler] %v\n", err)
+		if e.FailOpen {
+			return candidates
+		}
+		return nil
+	}
+
+	passed := make(map[string]bool)
+	for _, name := range result.Nodes {
+		passed[name] = true
+	}
+	var filtered []*node.Node
+	for _, n := range candidates {
+		if passed[n.Name] {
+			filtered = append(filtered, n)
+		} else {
+			log.Printf("[scheduler] extender filtered out node %s for task %s: %s\n", n.Name, t.ID, result.FailedNodes[n.Name])
+		}
+	}
+	return filtered
+}
+
+// Score returns the built-in scores adjusted by the extender, or the built-in scores alone if
+// the extender fails. Use ScoreNodes, or the package's Score function, to learn about a failure
+// of a fail-closed extender.
+func (e *Extender) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	nodeScores, _ := e.ScoreNodes(t, nodes)
+	return nodeScores
+}
+
+// ScoreNodes is like Score but returns an error if the extender failed and is fail-closed.
+func (e *Extender) ScoreNodes(t task.Task, nodes []*node.Node) (map[string]float64, error) {
+	nodeScores := e.Scheduler.Score(t, nodes)
+
+	var result []ExtenderScore
+	err := e.call("prioritize", ExtenderArgs{Task: t, Nodes: nodes}, &result)
+	if err != nil {
+		log.Printf("[scheduler] %v\n", err)
+		if e.FailOpen {
+			return nodeScores, nil
+		}
+		return nodeScores, err
+	}
+
+	for _, s := range result {
+		if _, ok := nodeScores[s.Node]; ok {
+			nodeScores[s.Node] += e.Weight * s.Score
+		}
+	}
+	return nodeScores, nil
+}
+
+func (e *Extender) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return e.Scheduler.Pick(scores, candidates)
+}
+
+func (e *Extender) call(verb string, args ExtenderArgs, result interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("unable to marshal extender args: %v", err)
+	}
+
+	client := e.client
+	if client == nil {
+		client = &http.Client{Timeout: e.Timeout}
+	}
+	url := fmt.Sprintf("%s/%s", e.URL, verb)
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("error calling extender %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("extender %s returned status %d", url, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("error decoding response from extender %s: %v", url, err)
+	}
+	return nil
+}
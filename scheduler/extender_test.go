@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+func extenderNodes() []*node.Node {
+	return []*node.Node{{Name: "worker-1"}, {Name: "worker-2"}}
+}
+
+func TestExtenderFiltersAndScores(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/filter":
+			json.NewEncoder(w).Encode(ExtenderFilterResult{
+				Nodes:       []string{"worker-2"},
+				FailedNodes: map[string]string{"worker-1": "no gpu"},
+			})
+		case "/prioritize":
+			json.NewEncoder(w).Encode([]ExtenderScore{{Node: "worker-1", Score: 2}, {Node: "unknown", Score: 5}})
+		}
+	}))
+	defer s.Close()
+	e := NewExtender(&MostAllocated{Name: "binpack"}, s.URL, time.Second, false, 0.5)
+
+	candidates := e.SelectCandidateNodes(task.Task{}, extenderNodes())
+	if len(candidates) != 1 || candidates[0].Name != "worker-2" {
+		t.Errorf("SelectCandidateNodes() = %v, want only worker-2", candidates)
+	}
+
+	scores, err := e.ScoreNodes(task.Task{}, extenderNodes())
+	if err != nil {
+		t.Fatalf("ScoreNodes() error = %v", err)
+	}
+	// the built-in score of an empty node is 1, the extender adds its score times the weight
+	want := map[string]float64{"worker-1": 2, "worker-2": 1}
+	if len(scores) != len(want) || scores["worker-1"] != want["worker-1"] || scores["worker-2"] != want["worker-2"] {
+		t.Errorf("ScoreNodes() = %v, want %v", scores, want)
+	}
+}
+
+func TestExtenderFailures(t *testing.T) {
+	failures := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+		{"timeout", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}},
+		{"malformed json", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{not json"))
+		}},
+	}
+
+	for _, f := range failures {
+		s := httptest.NewServer(f.handler)
+
+		open := NewExtender(&MostAllocated{Name: "binpack"}, s.URL, 50*time.Millisecond, true, 1)
+		if candidates := open.SelectCandidateNodes(task.Task{}, extenderNodes()); len(candidates) != 2 {
+			t.Errorf("%s: fail-open SelectCandidateNodes() = %v, want both nodes", f.name, candidates)
+		}
+		scores, err := open.ScoreNodes(task.Task{}, extenderNodes())
+		if err != nil || scores["worker-1"] != 1 || scores["worker-2"] != 1 {
+			t.Errorf("%s: fail-open ScoreNodes() = %v, %v, want the built-in scores", f.name, scores, err)
+		}
+
+		closed := NewExtender(&MostAllocated{Name: "binpack"}, s.URL, 50*time.Millisecond, false, 1)
+		if candidates := closed.SelectCandidateNodes(task.Task{}, extenderNodes()); len(candidates) != 0 {
+			t.Errorf("%s: fail-closed SelectCandidateNodes() = %v, want no nodes", f.name, candidates)
+		}
+		if _, err := Score(closed, task.Task{}, extenderNodes()); err == nil {
+			t.Errorf("%s: fail-closed Score() should return an error", f.name)
+		}
+
+		s.Close()
+	}
+}
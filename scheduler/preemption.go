@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// SelectVictims looks for a node where stopping some lower-priority tasks would free enough memory,
+// disk and cores for t. tasks maps a node name to the tasks currently placed on it. Fit is checked
+// here rather than with the scheduler's filter, since not every scheduler filters on resources and
+// an extender's filter is a request to another service. Nodes needing fewer victims, and victims
+// with lower priorities, are preferred. It returns a nil node if no amount of preemption makes t fit.
+func SelectVictims(t task.Task, nodes []*node.Node, tasks map[string][]*task.Task) (*node.Node, []*task.Task) {
+	var bestNode *node.Node
+	var bestVictims []*task.Task
+	for _, n := range nodes {
+		if !checkSchedulable(n) || !checkTaints(t, n) {
+			continue
+		}
+		victims := selectVictimsOnNode(t, n, tasks[n.Name])
+		if victims == nil {
+			continue
+		}
+		if bestNode == nil || preferVictims(victims, bestVictims) {
+			bestNode = n
+			bestVictims = victims
+		}
+	}
+	return bestNode, bestVictims
+}
+
+// selectVictimsOnNode removes the node's lower-priority tasks from a copy of the node, lowest priority
+// first, until t fits. It returns nil if t already fits, since stopping tasks wouldn't help then.
+func selectVictimsOnNode(t task.Task, n *node.Node, tasks []*task.Task) []*task.Task {
+	var lower []*task.Task
+	var cpu float64 // cores requested by the tasks left on the node
+	for _, lt := range tasks {
+		cpu += lt.Cpu
+		if lt.Priority() < t.Priority() {
+			lower = append(lower, lt)
+		}
+	}
+	sort.SliceStable(lower, func(i, j int) bool {
+		return lower[i].Priority() < lower[j].Priority()
+	})
+
+	sim := *n
+	if fitsNode(t, &sim, cpu) {
+		return nil
+	}
+	var victims []*task.Task
+	for _, v := range lower {
+		releaseTask(&sim, v)
+		cpu -= v.Cpu
+		victims = append(victims, v)
+		if fitsNode(t, &sim, cpu) {
+			return victims
+		}
+	}
+	return nil
+}
+
+// fitsNode reports whether the node has enough unallocated memory, disk and cores for t, given the
+// cores requested by the tasks on the node. Cores are only checked once the node reported them.
+func fitsNode(t task.Task, n *node.Node, cpu float64) bool {
+	return HasCapacity(t, n) && (n.Cores == 0 || cpu+t.Cpu <= float64(n.Cores))
+}
+
+// HasCapacity reports whether the node has enough unallocated memory and disk for t. Capacities the
+// node hasn't reported yet are assumed to fit.
+func HasCapacity(t task.Task, n *node.Node) bool {
+	if n.Disk > 0 && n.DiskAllocated+t.Disk > n.Disk {
+		return false
+	}
+	return checkMemory(t, n)
+}
+
+func releaseTask(n *node.Node, t *task.Task) {
+	if n.DiskAllocated >= t.Disk {
+		n.DiskAllocated -= t.Disk
+	} else {
+		n.DiskAllocated = 0
+	}
+	if n.MemoryAllocated >= t.Memory {
+		n.MemoryAllocated -= t.Memory
+	} else {
+		n.MemoryAllocated = 0
+	}
+	if n.TaskCount > 0 {
+		n.TaskCount--
+	}
+}
+
+func preferVictims(a, b []*task.Task) bool {
+	maxA, maxB := maxPriority(a), maxPriority(b)
+	if maxA != maxB {
+		return maxA < maxB
+	}
+	return len(a) < len(b)
+}
+
+func maxPriority(tasks []*task.Task) int {
+	p := tasks[0].Priority()
+	for _, t := range tasks[1:] {
+		if t.Priority() > p {
+			p = t.Priority()
+		}
+	}
+	return p
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// Node memory is in KiB and task memory in bytes, as reported by the workers.
+
+func TestSelectVictimsPrefersLowestPriority(t *testing.T) {
+	nodes := []*node.Node{
+		{Name: "worker-1", Memory: 1000, MemoryAllocated: 1_000_000},
+		{Name: "worker-2", Memory: 1000, MemoryAllocated: 1_000_000},
+	}
+	tasks := map[string][]*task.Task{
+		"worker-1": {
+			{Name: "low-1", PriorityClass: "low", Memory: 500_000},
+			{Name: "low-2", PriorityClass: "low", Memory: 500_000},
+		},
+		"worker-2": {
+			{Name: "low-3", PriorityClass: "low", Memory: 500_000},
+			{Name: "batch-1", PriorityClass: "batch", Memory: 500_000},
+		},
+	}
+
+	n, victims := SelectVictims(task.Task{PriorityClass: "high", Memory: 400_000}, nodes, tasks)
+	if n == nil || n.Name != "worker-2" {
+		t.Fatalf("SelectVictims() picked %v, want worker-2", n)
+	}
+	if len(victims) != 1 || victims[0].Name != "batch-1" {
+		t.Errorf("victims = %v, want only batch-1", victims)
+	}
+	if nodes[1].MemoryAllocated != 1_000_000 {
+		t.Errorf("SelectVictims() changed the allocation of the node to %d", nodes[1].MemoryAllocated)
+	}
+}
+
+func TestSelectVictimsPrefersFewerVictims(t *testing.T) {
+	nodes := []*node.Node{
+		{Name: "worker-1", Memory: 1000, MemoryAllocated: 900_000},
+		{Name: "worker-2", Memory: 1000, MemoryAllocated: 900_000},
+	}
+	tasks := map[string][]*task.Task{
+		"worker-1": {
+			{Name: "low-1", PriorityClass: "low", Memory: 300_000},
+			{Name: "low-2", PriorityClass: "low", Memory: 300_000},
+			{Name: "low-3", PriorityClass: "low", Memory: 300_000},
+		},
+		"worker-2": {
+			{Name: "low-4", PriorityClass: "low", Memory: 900_000},
+		},
+	}
+
+	n, victims := SelectVictims(task.Task{PriorityClass: "high", Memory: 800_000}, nodes, tasks)
+	if n == nil || n.Name != "worker-2" {
+		t.Fatalf("SelectVictims() picked %v, want worker-2", n)
+	}
+	if len(victims) != 1 {
+		t.Errorf("got %d victims, want 1", len(victims))
+	}
+}
+
+func TestSelectVictimsStopsLowestPriorityFirst(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, MemoryAllocated: 1_000_000}}
+	tasks := map[string][]*task.Task{
+		"worker-1": {
+			{Name: "high", PriorityClass: "high", Memory: 500_000},
+			{Name: "batch", PriorityClass: "batch", Memory: 250_000},
+			{Name: "low", PriorityClass: "low", Memory: 250_000},
+		},
+	}
+
+	_, victims := SelectVictims(task.Task{PriorityClass: "critical", Memory: 600_000}, nodes, tasks)
+	var names []string
+	for _, v := range victims {
+		names = append(names, v.Name)
+	}
+	if len(names) != 3 || names[0] != "batch" || names[1] != "low" || names[2] != "high" {
+		t.Errorf("victims = %v, want [batch low high]", names)
+	}
+}
+
+func TestSelectVictimsChecksDiskAndCores(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Cores: 4, Memory: 1000, Disk: 100, DiskAllocated: 60}}
+	tasks := map[string][]*task.Task{
+		"worker-1": {
+			{Name: "disk", PriorityClass: "low", Disk: 60},
+			{Name: "cpu", PriorityClass: "batch", Cpu: 3},
+		},
+	}
+
+	_, victims := SelectVictims(task.Task{PriorityClass: "high", Cpu: 2}, nodes, tasks)
+	if len(victims) != 1 || victims[0].Name != "cpu" {
+		t.Errorf("victims for a task short of cores = %v, want [cpu]", victims)
+	}
+	_, victims = SelectVictims(task.Task{PriorityClass: "high", Disk: 50}, nodes, tasks)
+	if len(victims) != 2 || victims[1].Name != "disk" {
+		t.Errorf("victims for a task short of disk = %v, want [cpu disk]", victims)
+	}
+}
+
+func TestSelectVictimsSkipsNodesTheTaskCannotUse(t *testing.T) {
+	nodes := []*node.Node{
+		{Name: "cordoned", Memory: 1000, MemoryAllocated: 1_000_000, Unschedulable: true},
+		{Name: "tainted", Memory: 1000, MemoryAllocated: 1_000_000, Taints: []node.Taint{{Key: "gpu", Effect: node.NoSchedule}}},
+	}
+	tasks := map[string][]*task.Task{
+		"cordoned": {{Name: "batch-1", PriorityClass: "batch", Memory: 1_000_000}},
+		"tainted":  {{Name: "batch-2", PriorityClass: "batch", Memory: 1_000_000}},
+	}
+
+	if n, _ := SelectVictims(task.Task{PriorityClass: "high", Memory: 500_000}, nodes, tasks); n != nil {
+		t.Errorf("SelectVictims() picked %s, want no node", n.Name)
+	}
+}
+
+func TestSelectVictimsNeverPreemptsEqualPriority(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, MemoryAllocated: 1_000_000}}
+	tasks := map[string][]*task.Task{
+		"worker-1": {
+			{Name: "default", Memory: 500_000},
+			{Name: "high", PriorityClass: "high", Memory: 500_000},
+		},
+	}
+
+	n, victims := SelectVictims(task.Task{PriorityClass: "normal", Memory: 500_000}, nodes, tasks)
+	if n != nil {
+		t.Errorf("SelectVictims() picked %s with victims %v, want no node", n.Name, victims)
+	}
+}
+
+func TestSelectVictimsWhenPreemptionDoesNotHelp(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, MemoryAllocated: 500_000}}
+	tasks := map[string][]*task.Task{
+		"worker-1": {{Name: "batch", PriorityClass: "batch", Memory: 500_000}},
+	}
+
+	if n, _ := SelectVictims(task.Task{PriorityClass: "critical", Memory: 2_000_000}, nodes, tasks); n != nil {
+		t.Errorf("SelectVictims() picked %s for a task larger than any node", n.Name)
+	}
+	// nothing needs to be stopped for a task that fits already
+	if n, _ := SelectVictims(task.Task{PriorityClass: "critical", Memory: 100_000}, nodes, tasks); n != nil {
+		t.Errorf("SelectVictims() picked %s for a task that fits", n.Name)
+	}
+}
+
+func TestHasCapacity(t *testing.T) {
+	n := &node.Node{Memory: 1000, MemoryAllocated: 600_000, Disk: 100, DiskAllocated: 50}
+	if !HasCapacity(task.Task{Memory: 400_000, Disk: 50}, n) {
+		t.Error("a task using the rest of the node should fit")
+	}
+	if HasCapacity(task.Task{Memory: 500_000}, n) || HasCapacity(task.Task{Disk: 60}, n) {
+		t.Error("a task needing more than is left should not fit")
+	}
+	if !HasCapacity(task.Task{Memory: 1 << 40, Disk: 1 << 40}, &node.Node{}) {
+		t.Error("a node that hasn't reported its capacity should be assumed to fit")
+	}
+}
@@ -11,13 +11,49 @@ type Scheduler interface {
 	Pick(scores map[string]float64, candidates []*node.Node) *node.Node
 }
 
+// FallibleScorer is implemented by schedulers whose scoring can fail in a way that must keep the
+// task from being placed, such as a fail-closed Extender.
+type FallibleScorer interface {
+	ScoreNodes(t task.Task, nodes []*node.Node) (map[string]float64, error)
+}
+
+// Score scores the nodes for t with s. An error means that no node must be picked.
+func Score(s Scheduler, t task.Task, nodes []*node.Node) (map[string]float64, error) {
+	if fs, ok := s.(FallibleScorer); ok {
+		return fs.ScoreNodes(t, nodes)
+	}
+	return s.Score(t, nodes), nil
+}
+
+// New returns the scheduler with the given name, falling back to round robin for unknown names.
+func New(schedulerType string) Scheduler {
+	switch schedulerType {
+	case "roundrobin":
+		return &RoundRobin{Name: "roundrobin"}
+	case "epvm":
+		return &Epvm{Name: "epvm"}
+	case "binpack":
+		return &MostAllocated{Name: "binpack"}
+	case "spread":
+		return &Spread{Name: "spread", ZoneWeight: 0.5}
+	default:
+		return &RoundRobin{Name: "roundrobin"}
+	}
+}
+
 type RoundRobin struct {
 	Name       string
 	LastWorker int
 }
 
 func (r *RoundRobin) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
-	return nodes
+	var candidates []*node.Node
+	for _, n := range nodes {
+		if checkSchedulable(n) && checkTaints(t, n) {
+			candidates = append(candidates, n)
+		}
+	}
+	return candidates
 }
 
 func (r *RoundRobin) Score(t task.Task, nodes []*node.Node) map[string]float64 {
@@ -37,6 +73,7 @@ func (r *RoundRobin) Score(t task.Task, nodes []*node.Node) map[string]float64 {
 		} else {
 			nodeScores[n.Name] = 1.0
 		}
+		nodeScores[n.Name] += taintPenalty(t, n)
 	}
 	return nodeScores
 }
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// Spread is a least-allocated scheduler that also balances tasks across zones. Nodes are grouped by
+// their node.ZoneLabel label; a node without one is treated as a zone of its own.
+type Spread struct {
+	Name string
+	// ZoneWeight is how much the zone balance counts against the node's own allocation, from 0 to 1.
+	ZoneWeight float64
+}
+
+func (s *Spread) SelectCandidateNodes(t task.Task, nodes []*node.Node) []*node.Node {
+	return selectFittingNodes(t, nodes)
+}
+
+func (s *Spread) Score(t task.Task, nodes []*node.Node) map[string]float64 {
+	zoneTasks := make(map[string]uint64)
+	var totalTasks uint64
+	for _, n := range nodes {
+		zoneTasks[zoneOf(n)] += n.TaskCount
+		totalTasks += n.TaskCount
+	}
+
+	nodeScores := make(map[string]float64)
+	for _, n := range nodes {
+		zoneShare := 0.0
+		if totalTasks > 0 {
+			zoneShare = float64(zoneTasks[zoneOf(n)]) / float64(totalTasks)
+		}
+		nodeScores[n.Name] = (1-s.ZoneWeight)*allocatedFraction(t, n) + s.ZoneWeight*zoneShare + taintPenalty(t, n)
+	}
+	return nodeScores
+}
+
+func (s *Spread) Pick(scores map[string]float64, candidates []*node.Node) *node.Node {
+	return pickLowest(scores, candidates)
+}
+
+func zoneOf(n *node.Node) string {
+	if zone, ok := n.Labels[node.ZoneLabel]; ok {
+		return zone
+	}
+	return n.Name
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// preferNoSchedulePenalty is added to a node's score for every PreferNoSchedule taint the task does not tolerate.
+// Both schedulers pick the lowest score, so the penalty pushes such nodes to the back of the line.
+const preferNoSchedulePenalty = 10.0
+
+func toleratesTaint(tol task.Toleration, taint node.Taint) bool {
+	if tol.Effect != "" && tol.Effect != string(taint.Effect) {
+		return false
+	}
+	if tol.Key == "" {
+		return tol.Operator == "Exists"
+	}
+	if tol.Key != taint.Key {
+		return false
+	}
+	if tol.Operator == "Exists" {
+		return true
+	}
+	return tol.Value == taint.Value
+}
+
+// ToleratesTaint reports whether any of the task's tolerations match the taint.
+func ToleratesTaint(t task.Task, taint node.Taint) bool {
+	for _, tol := range t.Tolerations {
+		if toleratesTaint(tol, taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchedulable reports whether the node accepts new tasks, i.e. it is not cordoned.
+func checkSchedulable(n *node.Node) bool {
+	return !n.Unschedulable
+}
+
+// checkTaints reports whether the task tolerates all the NoSchedule and NoExecute taints of the node.
+func checkTaints(t task.Task, n *node.Node) bool {
+	for _, taint := range n.Taints {
+		if taint.Effect == node.PreferNoSchedule {
+			continue
+		}
+		if !ToleratesTaint(t, taint) {
+			return false
+		}
+	}
+	return true
+}
+
+func taintPenalty(t task.Task, n *node.Node) float64 {
+	penalty := 0.0
+	for _, taint := range n.Taints {
+		if taint.Effect == node.PreferNoSchedule && !ToleratesTaint(t, taint) {
+			penalty += preferNoSchedulePenalty
+		}
+	}
+	return penalty
+}
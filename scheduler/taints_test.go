@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+func TestToleratesTaint(t *testing.T) {
+	taint := node.Taint{Key: "gpu", Value: "nvidia", Effect: node.NoSchedule}
+
+	tests := []struct {
+		tol  task.Toleration
+		want bool
+	}{
+		{task.Toleration{Key: "gpu", Operator: "Equal", Value: "nvidia"}, true},
+		{task.Toleration{Key: "gpu", Value: "nvidia"}, true},
+		{task.Toleration{Key: "gpu", Operator: "Equal", Value: "amd"}, false},
+		{task.Toleration{Key: "gpu", Operator: "Exists"}, true},
+		{task.Toleration{Key: "ssd", Operator: "Exists"}, false},
+		{task.Toleration{Operator: "Exists"}, true},
+		{task.Toleration{Operator: "Equal"}, false},
+		{task.Toleration{Key: "gpu", Operator: "Exists", Effect: "NoSchedule"}, true},
+		{task.Toleration{Key: "gpu", Operator: "Exists", Effect: "NoExecute"}, false},
+		{task.Toleration{Operator: "Exists", Effect: "PreferNoSchedule"}, false},
+	}
+	for _, tt := range tests {
+		if got := toleratesTaint(tt.tol, taint); got != tt.want {
+			t.Errorf("toleratesTaint(%+v) = %v, want %v", tt.tol, got, tt.want)
+		}
+	}
+}
+
+func TestToleratesTaintWithAnyToleration(t *testing.T) {
+	taint := node.Taint{Key: "dedicated", Value: "db", Effect: node.NoExecute}
+	tk := task.Task{Tolerations: []task.Toleration{
+		{Key: "gpu", Operator: "Exists"},
+		{Key: "dedicated", Operator: "Equal", Value: "db"},
+	}}
+	if !ToleratesTaint(tk, taint) {
+		t.Error("the second toleration should match the taint")
+	}
+	if ToleratesTaint(task.Task{}, taint) {
+		t.Error("a task without tolerations should not tolerate the taint")
+	}
+}
+
+func TestCheckTaints(t *testing.T) {
+	n := &node.Node{Name: "worker-1", Taints: []node.Taint{
+		{Key: "gpu", Effect: node.NoSchedule},
+		{Key: "spot", Effect: node.PreferNoSchedule},
+	}}
+
+	if checkTaints(task.Task{}, n) {
+		t.Error("a task not tolerating the NoSchedule taint should not fit")
+	}
+	gpu := task.Task{Tolerations: []task.Toleration{{Key: "gpu", Operator: "Exists"}}}
+	if !checkTaints(gpu, n) {
+		t.Error("PreferNoSchedule taints should not keep a task off the node")
+	}
+
+	n.Taints = append(n.Taints, node.Taint{Key: "maintenance", Effect: node.NoExecute})
+	if checkTaints(gpu, n) {
+		t.Error("a task not tolerating the NoExecute taint should not fit")
+	}
+}
+
+func TestTaintPenalty(t *testing.T) {
+	n := &node.Node{Name: "worker-1", Taints: []node.Taint{
+		{Key: "spot", Effect: node.PreferNoSchedule},
+		{Key: "slow-disk", Effect: node.PreferNoSchedule},
+		{Key: "gpu", Effect: node.NoSchedule},
+	}}
+
+	if got := taintPenalty(task.Task{}, n); got != 2*preferNoSchedulePenalty {
+		t.Errorf("taintPenalty() = %v, want one penalty per PreferNoSchedule taint", got)
+	}
+	spot := task.Task{Tolerations: []task.Toleration{{Key: "spot", Operator: "Exists"}}}
+	if got := taintPenalty(spot, n); got != preferNoSchedulePenalty {
+		t.Errorf("taintPenalty() = %v, want no penalty for the tolerated taint", got)
+	}
+	if got := taintPenalty(task.Task{}, &node.Node{}); got != 0 {
+		t.Errorf("taintPenalty() of an untainted node = %v, want 0", got)
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultScaleDownStabilization is how many seconds a lower replica count must be recommended
+	// before the autoscaler scales down, so that short dips in load don't cause flapping.
+	DefaultScaleDownStabilization = 300
+	// Tolerance is the relative difference between the current and target utilization below
+	// which the autoscaler leaves the replica count alone.
+	Tolerance = 0.1
+)
+
+// HorizontalAutoscaler adjusts the replicas of a service to keep the average CPU or memory
+// utilization of its replicas, relative to what each replica requested, close to a target.
+// With both targets set, the larger of the two replica counts wins.
+// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#algorithm-details
+type HorizontalAutoscaler struct {
+	Name                    string
+	Service                 string
+	MinReplicas             int
+	MaxReplicas             int
+	TargetCpuUtilization    float64 // percent of the requested Cpu, 0 disables
+	TargetMemoryUtilization float64 // percent of the requested Memory, 0 disables
+	// The replica count is the lowest recommended within the last ScaleUpStabilization seconds
+	// when scaling up, and the highest recommended within the last ScaleDownStabilization seconds
+	// when scaling down. A zero ScaleDownStabilization uses DefaultScaleDownStabilization.
+	ScaleUpStabilization   int
+	ScaleDownStabilization int
+
+	CurrentCpuUtilization    float64
+	CurrentMemoryUtilization float64
+	DesiredReplicas          int
+	LastScaleTime            time.Time
+
+	recommendations []recommendation
+}
+
+type recommendation struct {
+	replicas int
+	time     time.Time
+}
+
+// Utilization is the average usage of a service's replicas in percent of their requests. A
+// value below zero means it could not be measured.
+type Utilization struct {
+	Cpu    float64
+	Memory float64
+}
+
+func (h *HorizontalAutoscaler) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("autoscaler name must not be empty")
+	}
+	if h.Service == "" {
+		return fmt.Errorf("autoscaler %s: service must not be empty", h.Name)
+	}
+	if h.MinReplicas < 1 || h.MaxReplicas < h.MinReplicas {
+		return fmt.Errorf("autoscaler %s: replicas must satisfy 1 <= min <= max", h.Name)
+	}
+	if h.TargetCpuUtilization <= 0 && h.TargetMemoryUtilization <= 0 {
+		return fmt.Errorf("autoscaler %s: a CPU or memory utilization target is required", h.Name)
+	}
+	if h.ScaleUpStabilization < 0 || h.ScaleDownStabilization < 0 {
+		return fmt.Errorf("autoscaler %s: stabilization windows must not be negative", h.Name)
+	}
+	return nil
+}
+
+// Recommend returns the number of replicas the service should run, given the current count and
+// the measured utilization, and records the recommendation for stabilization.
+func (h *HorizontalAutoscaler) Recommend(current int, u Utilization, now time.Time) int {
+	h.CurrentCpuUtilization = u.Cpu
+	h.CurrentMemoryUtilization = u.Memory
+
+	desired := -1
+	if h.TargetCpuUtilization > 0 && u.Cpu >= 0 {
+		desired = max(desired, replicasFor(current, u.Cpu, h.TargetCpuUtilization))
+	}
+	if h.TargetMemoryUtilization > 0 && u.Memory >= 0 {
+		desired = max(desired, replicasFor(current, u.Memory, h.TargetMemoryUtilization))
+	}
+	if desired < 0 {
+		// nothing could be measured, e.g. no replica is running yet
+		desired = current
+	}
+	desired = min(max(desired, h.MinReplicas), h.MaxReplicas)
+
+	h.recommendations = append(h.recommendations, recommendation{replicas: desired, time: now})
+	h.DesiredReplicas = h.stabilize(current, now)
+	return h.DesiredReplicas
+}
+
+func replicasFor(current int, utilization float64, target float64) int {
+	ratio := utilization / target
+	if math.Abs(ratio-1) <= Tolerance {
+		return current
+	}
+	return int(math.Ceil(float64(current) * ratio))
+}
+
+// stabilize scales up only to the lowest count recommended within the scale up window and down
+// only to the highest count recommended within the scale down window, dropping recommendations
+// older than both windows.
+func (h *HorizontalAutoscaler) stabilize(current int, now time.Time) int {
+	upLimit := math.MaxInt
+	downLimit := math.MinInt
+	upWindow := time.Duration(h.ScaleUpStabilization) * time.Second
+	downWindow := time.Duration(h.ScaleDownStabilization) * time.Second
+	longest := max(upWindow, downWindow)
+
+	var kept []recommendation
+	for _, r := range h.recommendations {
+		age := now.Sub(r.time)
+		if age > longest {
+			continue
+		}
+		kept = append(kept, r)
+		if age <= upWindow {
+			upLimit = min(upLimit, r.replicas)
+		}
+		if age <= downWindow {
+			downLimit = max(downLimit, r.replicas)
+		}
+	}
+	h.recommendations = kept
+
+	switch {
+	case current < upLimit:
+		return upLimit
+	case current > downLimit:
+		return downLimit
+	default:
+		return current
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecommendTargetsUtilization(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		h       HorizontalAutoscaler
+		current int
+		u       Utilization
+		want    int
+	}{
+		{"cpu above target", HorizontalAutoscaler{MaxReplicas: 10, TargetCpuUtilization: 50}, 2, Utilization{Cpu: 100, Memory: -1}, 4},
+		{"memory below target", HorizontalAutoscaler{MaxReplicas: 10, TargetMemoryUtilization: 60}, 4, Utilization{Cpu: -1, Memory: 30}, 2},
+		{"within tolerance", HorizontalAutoscaler{MaxReplicas: 10, TargetCpuUtilization: 50}, 3, Utilization{Cpu: 54}, 3},
+		{"larger of both targets", HorizontalAutoscaler{MaxReplicas: 10, TargetCpuUtilization: 50, TargetMemoryUtilization: 50}, 2, Utilization{Cpu: 100, Memory: 150}, 6},
+		{"nothing measured", HorizontalAutoscaler{MaxReplicas: 10, TargetCpuUtilization: 50}, 3, Utilization{Cpu: -1, Memory: -1}, 3},
+		{"clamped to max", HorizontalAutoscaler{MinReplicas: 2, MaxReplicas: 5, TargetCpuUtilization: 50}, 2, Utilization{Cpu: 400}, 5},
+		{"clamped to min", HorizontalAutoscaler{MinReplicas: 2, MaxReplicas: 5, TargetCpuUtilization: 50}, 3, Utilization{Cpu: 10}, 2},
+	}
+	for _, tt := range tests {
+		if got := tt.h.Recommend(tt.current, tt.u, now); got != tt.want {
+			t.Errorf("%s: Recommend(%d, %+v) = %d, want %d", tt.name, tt.current, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestRecommendScaleUpStabilization(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := HorizontalAutoscaler{MinReplicas: 1, MaxReplicas: 10, TargetCpuUtilization: 50, ScaleUpStabilization: 60}
+
+	if got := h.Recommend(2, Utilization{Cpu: 50}, start); got != 2 {
+		t.Fatalf("Recommend() at target = %d, want 2", got)
+	}
+	// the lower recommendation from the start of the window holds the count back
+	if got := h.Recommend(2, Utilization{Cpu: 150}, start.Add(30*time.Second)); got != 2 {
+		t.Errorf("Recommend() within the scale up window = %d, want 2", got)
+	}
+	if got := h.Recommend(2, Utilization{Cpu: 150}, start.Add(61*time.Second)); got != 6 {
+		t.Errorf("Recommend() after the scale up window = %d, want 6", got)
+	}
+}
+
+func TestRecommendScaleDownStabilization(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := HorizontalAutoscaler{MinReplicas: 1, MaxReplicas: 10, TargetCpuUtilization: 50, ScaleDownStabilization: 300}
+
+	if got := h.Recommend(4, Utilization{Cpu: 50}, start); got != 4 {
+		t.Fatalf("Recommend() at target = %d, want 4", got)
+	}
+	if got := h.Recommend(4, Utilization{Cpu: 25}, start.Add(100*time.Second)); got != 4 {
+		t.Errorf("Recommend() within the scale down window = %d, want 4", got)
+	}
+	if got := h.Recommend(4, Utilization{Cpu: 25}, start.Add(301*time.Second)); got != 2 {
+		t.Errorf("Recommend() after the scale down window = %d, want 2", got)
+	}
+	if len(h.recommendations) != 2 {
+		t.Errorf("kept %d recommendations, want 2 once the first left the window", len(h.recommendations))
+	}
+}
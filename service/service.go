@@ -0,0 +1,37 @@
+// Package service defines replicated services, sets of identical tasks kept running by the
+// manager, and the autoscalers that adjust their number of replicas.
+package service
+
+import (
+	"fmt"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// Service keeps Replicas copies of Template running. Each replica is a task created from the
+// template with its own ID and its Service field set to the service's name.
+type Service struct {
+	Name     string
+	Replicas int
+	Template task.Task
+}
+
+func (s *Service) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("service name must not be empty")
+	}
+	if s.Replicas < 0 {
+		return fmt.Errorf("service %s: replicas must not be negative", s.Name)
+	}
+	if s.Template.Image == "" {
+		return fmt.Errorf("service %s: template image must not be empty", s.Name)
+	}
+	return nil
+}
+
+// Status describes the replicas of a service as last seen by the manager.
+type Status struct {
+	Service
+	Running int // replicas in the Running state
+	Active  int // replicas that are pending, scheduled, running or being rescheduled
+}
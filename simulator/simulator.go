@@ -0,0 +1,281 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// Submission is one line of a workload trace: a task submitted at a point in virtual time
+// that runs for Duration once it is placed. Times are in seconds since the start of the trace.
+type Submission struct {
+	At       float64
+	Duration float64
+	Task     task.Task
+}
+
+// Report summarizes a simulation run.
+type Report struct {
+	Scheduler         string
+	Submitted         int
+	Placed            int
+	Failed            int     // tasks that were still pending when the trace ended
+	RejectedPlacement int     // picks onto a node without enough capacity left
+	AvgPendingTime    float64 // seconds between submission and placement, over placed tasks
+	MaxPendingTime    float64
+	MemoryUtilization float64 // time-weighted share of the cluster's memory allocated to tasks
+	DiskUtilization   float64 // time-weighted share of the cluster's disk allocated to tasks
+	Fragmentation     float64 // time-weighted 1 - (largest free memory block / total free memory)
+	Makespan          float64 // seconds until the last task finished
+}
+
+type runningTask struct {
+	t      task.Task
+	node   *node.Node
+	finish float64
+}
+
+// Simulator replays a trace against a set of synthetic nodes using a virtual clock, so schedulers
+// can be compared without workers or Docker.
+type Simulator struct {
+	Scheduler   scheduler.Scheduler
+	Name        string
+	Nodes       []*node.Node
+	Submissions []Submission
+
+	now     float64
+	pending []Submission
+	running []runningTask
+	report  Report
+
+	pendingTimeTotal float64
+	memArea          float64
+	diskArea         float64
+	fragArea         float64
+}
+
+func New(name string, s scheduler.Scheduler, nodes []*node.Node, submissions []Submission) *Simulator {
+	subs := append([]Submission{}, submissions...)
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].At < subs[j].At
+	})
+	return &Simulator{
+		Scheduler:   s,
+		Name:        name,
+		Nodes:       nodes,
+		Submissions: subs,
+	}
+}
+
+// Run advances the virtual clock from event to event (task submissions and completions) and tries
+// to place every pending task after each one. It returns when no more events are left.
+func (s *Simulator) Run() Report {
+	s.report = Report{Scheduler: s.Name, Submitted: len(s.Submissions)}
+	next := 0
+	for next < len(s.Submissions) || len(s.running) > 0 {
+		at := s.nextEventTime(next)
+		s.advance(at)
+
+		s.finishTasks()
+		for next < len(s.Submissions) && s.Submissions[next].At <= s.now {
+			s.pending = append(s.pending, s.Submissions[next])
+			next++
+		}
+		s.schedulePending()
+	}
+
+	s.report.Failed = len(s.pending)
+	s.report.Makespan = s.now
+	if s.report.Placed > 0 {
+		s.report.AvgPendingTime = s.pendingTimeTotal / float64(s.report.Placed)
+	}
+	if s.now > 0 {
+		s.report.MemoryUtilization = s.memArea / s.now
+		s.report.DiskUtilization = s.diskArea / s.now
+		s.report.Fragmentation = s.fragArea / s.now
+	}
+	return s.report
+}
+
+func (s *Simulator) nextEventTime(next int) float64 {
+	at := -1.0
+	if next < len(s.Submissions) {
+		at = s.Submissions[next].At
+	}
+	for _, r := range s.running {
+		if at < 0 || r.finish < at {
+			at = r.finish
+		}
+	}
+	return at
+}
+
+// advance moves the clock forward, accumulating the time-weighted cluster metrics for the interval.
+func (s *Simulator) advance(at float64) {
+	if at <= s.now {
+		return
+	}
+	elapsed := at - s.now
+	mem, disk := s.utilization()
+	s.memArea += mem * elapsed
+	s.diskArea += disk * elapsed
+	s.fragArea += s.fragmentation() * elapsed
+	s.now = at
+}
+
+func (s *Simulator) finishTasks() {
+	var running []runningTask
+	for _, r := range s.running {
+		if r.finish > s.now {
+			running = append(running, r)
+			continue
+		}
+		r.node.MemoryAllocated -= r.t.Memory
+		r.node.DiskAllocated -= r.t.Disk
+		r.node.TaskCount--
+	}
+	s.running = running
+}
+
+// schedulePending tries each pending task once, highest priority first, in submission order otherwise.
+func (s *Simulator) schedulePending() {
+	sort.SliceStable(s.pending, func(i, j int) bool {
+		return s.pending[i].Task.Priority() > s.pending[j].Task.Priority()
+	})
+
+	var stillPending []Submission
+	for _, p := range s.pending {
+		t := p.Task
+		candidates := s.Scheduler.SelectCandidateNodes(t, s.Nodes)
+		if len(candidates) == 0 {
+			stillPending = append(stillPending, p)
+			continue
+		}
+		scores, err := scheduler.Score(s.Scheduler, t, candidates)
+		var n *node.Node
+		if err == nil {
+			n = s.Scheduler.Pick(scores, candidates)
+		}
+		if n == nil {
+			stillPending = append(stillPending, p)
+			continue
+		}
+		if !fits(t, n) {
+			s.report.RejectedPlacement++
+			stillPending = append(stillPending, p)
+			continue
+		}
+
+		n.MemoryAllocated += t.Memory
+		n.DiskAllocated += t.Disk
+		n.TaskCount++
+		s.running = append(s.running, runningTask{t: t, node: n, finish: s.now + p.Duration})
+
+		wait := s.now - p.At
+		s.pendingTimeTotal += wait
+		if wait > s.report.MaxPendingTime {
+			s.report.MaxPendingTime = wait
+		}
+		s.report.Placed++
+	}
+	s.pending = stillPending
+}
+
+// fits checks the node's real capacity, since not every scheduler filters on resources. Memory
+// follows the same units as the schedulers: node memory in KiB and task memory in bytes.
+func fits(t task.Task, n *node.Node) bool {
+	if n.DiskAllocated+t.Disk > n.Disk {
+		return false
+	}
+	return (n.MemoryAllocated+t.Memory)/1000 <= n.Memory
+}
+
+func (s *Simulator) utilization() (float64, float64) {
+	var memTotal, memUsed, diskTotal, diskUsed float64
+	for _, n := range s.Nodes {
+		memTotal += float64(n.Memory)
+		memUsed += float64(n.MemoryAllocated) / 1000
+		diskTotal += float64(n.Disk)
+		diskUsed += float64(n.DiskAllocated)
+	}
+
+	var mem, disk float64
+	if memTotal > 0 {
+		mem = memUsed / memTotal
+	}
+	if diskTotal > 0 {
+		disk = diskUsed / diskTotal
+	}
+	return mem, disk
+}
+
+// fragmentation is 0 when all free memory is on a single node and approaches 1 as it is
+// scattered in small pieces across many nodes.
+func (s *Simulator) fragmentation() float64 {
+	var free, largest float64
+	for _, n := range s.Nodes {
+		f := float64(n.Memory) - float64(n.MemoryAllocated)/1000
+		if f < 0 {
+			f = 0
+		}
+		free += f
+		if f > largest {
+			largest = f
+		}
+	}
+	if free == 0 {
+		return 0.00
+	}
+	return 1 - largest/free
+}
+
+// LoadNodes reads a JSON array of nodes with their capacities.
+func LoadNodes(filename string) ([]*node.Node, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read nodes file %s: %v", filename, err)
+	}
+
+	var nodes []*node.Node
+	err = json.Unmarshal(data, &nodes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode nodes file %s: %v", filename, err)
+	}
+	return nodes, nil
+}
+
+// LoadTrace reads a trace with one JSON encoded Submission per line. Blank lines are skipped.
+func LoadTrace(filename string) ([]Submission, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open trace file %s: %v", filename, err)
+	}
+	defer f.Close()
+
+	var subs []Submission
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sub Submission
+		err := json.Unmarshal(scanner.Bytes(), &sub)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode line %d of %s: %v", line, filename, err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading trace file %s: %v", filename, err)
+	}
+	return subs, nil
+}
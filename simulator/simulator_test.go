@@ -0,0 +1,69 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/scheduler"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// The node has 1000 KiB of memory; task memory is in bytes.
+
+func TestRunWaitsForCapacity(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, Disk: 100}}
+	trace := []Submission{
+		{At: 0, Duration: 10, Task: task.Task{Name: "a", Memory: 800_000}},
+		{At: 1, Duration: 5, Task: task.Task{Name: "b", Memory: 800_000}},
+	}
+
+	r := New("binpack", &scheduler.MostAllocated{}, nodes, trace).Run()
+	if r.Scheduler != "binpack" || r.Submitted != 2 || r.Placed != 2 || r.Failed != 0 {
+		t.Fatalf("Run() = %+v, want both tasks placed", r)
+	}
+	// b waits from 1 until a finishes at 10, then runs until 15
+	if r.Makespan != 15 {
+		t.Errorf("Makespan = %v, want 15", r.Makespan)
+	}
+	if r.MaxPendingTime != 9 || r.AvgPendingTime != 4.5 {
+		t.Errorf("pending time max %v, avg %v, want 9 and 4.5", r.MaxPendingTime, r.AvgPendingTime)
+	}
+	if nodes[0].MemoryAllocated != 0 || nodes[0].TaskCount != 0 {
+		t.Errorf("node still has %d bytes allocated to %d tasks", nodes[0].MemoryAllocated, nodes[0].TaskCount)
+	}
+}
+
+func TestRunPlacesHigherPriorityFirst(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, Disk: 100}}
+	trace := []Submission{
+		{At: 0, Duration: 10, Task: task.Task{Name: "a", Memory: 800_000}},
+		{At: 1, Duration: 5, Task: task.Task{Name: "low", PriorityClass: "low", Memory: 800_000}},
+		{At: 2, Duration: 5, Task: task.Task{Name: "high", PriorityClass: "high", Memory: 800_000}},
+	}
+
+	r := New("binpack", &scheduler.MostAllocated{}, nodes, trace).Run()
+	// high runs from 10 to 15 and low from 15 to 20
+	if r.Placed != 3 || r.Makespan != 20 || r.MaxPendingTime != 14 {
+		t.Errorf("Run() = %+v, want 3 placed, makespan 20 and the low priority task waiting 14s", r)
+	}
+}
+
+func TestRunCountsTasksThatNeverFit(t *testing.T) {
+	nodes := []*node.Node{{Name: "worker-1", Memory: 1000, Disk: 100}}
+	trace := []Submission{
+		{At: 0, Duration: 10, Task: task.Task{Name: "huge", Memory: 2_000_000}},
+		{At: 5, Duration: 10, Task: task.Task{Name: "small", Memory: 100_000}},
+	}
+
+	r := New("binpack", &scheduler.MostAllocated{}, nodes, trace).Run()
+	if r.Placed != 1 || r.Failed != 1 || r.Makespan != 15 {
+		t.Errorf("Run() = %+v, want 1 placed, 1 failed and makespan 15", r)
+	}
+}
+
+func TestRunWithoutSubmissions(t *testing.T) {
+	r := New("binpack", &scheduler.MostAllocated{}, []*node.Node{{Name: "worker-1", Memory: 1000}}, nil).Run()
+	if r.Submitted != 0 || r.Makespan != 0 || r.MemoryUtilization != 0 {
+		t.Errorf("Run() = %+v, want an empty report", r)
+	}
+}
@@ -0,0 +1,233 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// ring is a fixed size buffer that overwrites its oldest items once full.
+type ring[T any] struct {
+	mu    sync.Mutex
+	items []T
+	next  int
+	count int
+}
+
+func (r *ring[T]) add(item T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+	if r.count < len(r.items) {
+		r.count++
+	}
+}
+
+// all returns the items in the buffer, oldest first.
+func (r *ring[T]) all() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := make([]T, 0, r.count)
+	start := (r.next - r.count + len(r.items)) % len(r.items)
+	for i := 0; i < r.count; i++ {
+		items = append(items, r.items[(start+i)%len(r.items)])
+	}
+	return items
+}
+
+// History keeps the most recent host stats samples of a worker.
+type History struct {
+	ring[Stats]
+}
+
+func NewHistory(size int) *History {
+	return &History{ring[Stats]{items: make([]Stats, size)}}
+}
+
+func (h *History) Add(s Stats) {
+	h.add(s)
+}
+
+// Point is one entry of a host's stats time series. Rates are computed against the previous
+// entry, or against the sample preceding the series for the first one.
+type Point struct {
+	Timestamp            time.Time
+	CpuPercent           float64   // 100% is all cores fully used
+	CoreCpuPercent       []float64 // 100% is the core fully used
+	MemUsedKb            uint64
+	MemAvailableKb       uint64
+	DiskUsed             uint64
+	DiskFree             uint64
+	DiskReadBytesPerSec  float64
+	DiskWriteBytesPerSec float64
+	NetRxBytesPerSec     float64
+	NetTxBytesPerSec     float64
+	Load1                float64
+	CpuPressure          float64 // share of the last 10 seconds some tasks were stalled on CPU, in percent
+	MemoryPressure       float64 // likewise for memory
+	IoPressure           float64 // likewise for IO
+}
+
+// Series returns the samples taken at or after since, keeping at most one per step. A zero step
+// keeps every sample.
+func (h *History) Series(since time.Time, step time.Duration) []Point {
+	points := []Point{}
+	var prev *Stats
+	for _, s := range downsample(h.all(), since, step, func(s Stats) time.Time { return s.Timestamp }) {
+		cur := s.item
+		if s.prev != nil {
+			prev = s.prev
+		}
+		points = append(points, newPoint(prev, &cur))
+		prev = &cur
+	}
+	return points
+}
+
+func newPoint(prev *Stats, cur *Stats) Point {
+	p := Point{Timestamp: cur.Timestamp}
+	if cur.MemStats != nil {
+		p.MemUsedKb = cur.MemUsedKb()
+		p.MemAvailableKb = cur.MemAvailableKb()
+	}
+	if cur.DiskStats != nil {
+		p.DiskUsed = cur.DiskUsed()
+		p.DiskFree = cur.DiskFree()
+	}
+	if cur.LoadStats != nil {
+		p.Load1 = cur.LoadStats.Last1Min
+	}
+	if ps := cur.PressureStats; ps != nil {
+		if ps.Cpu != nil {
+			p.CpuPressure = ps.Cpu.Some.Avg10
+		}
+		if ps.Memory != nil {
+			p.MemoryPressure = ps.Memory.Some.Avg10
+		}
+		if ps.Io != nil {
+			p.IoPressure = ps.Io.Some.Avg10
+		}
+	}
+	if prev == nil {
+		return p
+	}
+
+	p.CpuPercent = CpuUsageBetween(prev, cur) * 100
+	for _, usage := range CoreUsageBetween(prev, cur) {
+		p.CoreCpuPercent = append(p.CoreCpuPercent, usage*100)
+	}
+	seconds := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if seconds <= 0 {
+		return p
+	}
+	if prev.DiskIOStats != nil && cur.DiskIOStats != nil {
+		p.DiskReadBytesPerSec = rate(prev.DiskIOStats.ReadBytes, cur.DiskIOStats.ReadBytes, seconds)
+		p.DiskWriteBytesPerSec = rate(prev.DiskIOStats.WriteBytes, cur.DiskIOStats.WriteBytes, seconds)
+	}
+	prevRx, prevTx := prev.NetBytes()
+	curRx, curTx := cur.NetBytes()
+	p.NetRxBytesPerSec = rate(prevRx, curRx, seconds)
+	p.NetTxBytesPerSec = rate(prevTx, curTx, seconds)
+	return p
+}
+
+// TaskHistory keeps the most recent stats samples of a task's container.
+type TaskHistory struct {
+	ring[TaskStats]
+}
+
+func NewTaskHistory(size int) *TaskHistory {
+	return &TaskHistory{ring[TaskStats]{items: make([]TaskStats, size)}}
+}
+
+func (h *TaskHistory) Add(s TaskStats) {
+	h.add(s)
+}
+
+// Latest returns the most recent sample, or nil if there is none.
+func (h *TaskHistory) Latest() *TaskStats {
+	items := h.all()
+	if len(items) == 0 {
+		return nil
+	}
+	return &items[len(items)-1]
+}
+
+// TaskPoint is one entry of a task's stats time series. Rates are computed like for Point.
+type TaskPoint struct {
+	Timestamp             time.Time
+	CpuPercent            float64 // like `docker stats`, 100% is one fully used core
+	MemoryUsage           uint64
+	MemoryPercent         float64
+	NetworkRxBytesPerSec  float64
+	NetworkTxBytesPerSec  float64
+	BlockReadBytesPerSec  float64
+	BlockWriteBytesPerSec float64
+}
+
+func (h *TaskHistory) Series(since time.Time, step time.Duration) []TaskPoint {
+	points := []TaskPoint{}
+	var prev *TaskStats
+	for _, s := range downsample(h.all(), since, step, func(s TaskStats) time.Time { return s.Timestamp }) {
+		cur := s.item
+		if s.prev != nil {
+			prev = s.prev
+		}
+
+		p := TaskPoint{
+			Timestamp:     cur.Timestamp,
+			CpuPercent:    cur.CpuPercent,
+			MemoryUsage:   cur.MemoryUsage,
+			MemoryPercent: cur.MemoryPercent(),
+		}
+		if prev != nil {
+			seconds := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+			if seconds > 0 {
+				p.NetworkRxBytesPerSec = rate(prev.NetworkRx, cur.NetworkRx, seconds)
+				p.NetworkTxBytesPerSec = rate(prev.NetworkTx, cur.NetworkTx, seconds)
+				p.BlockReadBytesPerSec = rate(prev.BlockRead, cur.BlockRead, seconds)
+				p.BlockWriteBytesPerSec = rate(prev.BlockWrite, cur.BlockWrite, seconds)
+			}
+		}
+		points = append(points, p)
+		prev = &cur
+	}
+	return points
+}
+
+type sample[T any] struct {
+	item T
+	prev *T // the sample preceding the series, set on the first entry only
+}
+
+// downsample picks the items taken at or after since, at most one per step, along with the
+// item right before the first one so that rates can be computed for every entry.
+func downsample[T any](items []T, since time.Time, step time.Duration, timestamp func(T) time.Time) []sample[T] {
+	var picked []sample[T]
+	var last time.Time
+	for i, item := range items {
+		ts := timestamp(item)
+		if ts.Before(since) {
+			continue
+		}
+		if len(picked) > 0 && ts.Sub(last) < step {
+			continue
+		}
+
+		s := sample[T]{item: item}
+		if len(picked) == 0 && i > 0 {
+			s.prev = &items[i-1]
+		}
+		picked = append(picked, s)
+		last = ts
+	}
+	return picked
+}
+
+// rate returns the per second increase of a counter, treating a reset as no increase.
+func rate(prev uint64, cur uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0.00
+	}
+	return float64(cur-prev) / seconds
+}
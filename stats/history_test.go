@@ -0,0 +1,173 @@
+package stats
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/c9s/goprocinfo/linux"
+)
+
+func TestRingWrapsAround(t *testing.T) {
+	r := ring[int]{items: make([]int, 3)}
+	if got := r.all(); len(got) != 0 {
+		t.Fatalf("all() of an empty ring = %v", got)
+	}
+
+	r.add(1)
+	r.add(2)
+	if got := r.all(); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("all() = %v, want [1 2]", got)
+	}
+
+	for i := 3; i <= 7; i++ {
+		r.add(i)
+	}
+	if got := r.all(); !slices.Equal(got, []int{5, 6, 7}) {
+		t.Errorf("all() after wrapping around = %v, want the 3 newest oldest first", got)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// one sample every 10 seconds, from 0 to 90
+	var seconds []int
+	for i := 0; i < 10; i++ {
+		seconds = append(seconds, i*10)
+	}
+	timestamp := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	items := func(samples []sample[int]) []int {
+		var picked []int
+		for _, s := range samples {
+			picked = append(picked, s.item)
+		}
+		return picked
+	}
+
+	all := downsample(seconds, start, 0, timestamp)
+	if !slices.Equal(items(all), seconds) || all[0].prev != nil {
+		t.Errorf("downsample() without since and step = %v, want every sample", items(all))
+	}
+
+	since := downsample(seconds, start.Add(65*time.Second), 0, timestamp)
+	if !slices.Equal(items(since), []int{70, 80, 90}) {
+		t.Errorf("downsample() since 65s = %v, want [70 80 90]", items(since))
+	}
+	if since[0].prev == nil || *since[0].prev != 60 || since[1].prev != nil {
+		t.Error("only the first sample should point at the one before it")
+	}
+
+	// samples closer than step to the last picked one are skipped
+	stepped := downsample(seconds, start.Add(40*time.Second), 25*time.Second, timestamp)
+	if !slices.Equal(items(stepped), []int{40, 70}) {
+		t.Errorf("downsample() with a 25s step = %v, want [40 70]", items(stepped))
+	}
+
+	if got := downsample(seconds, start.Add(time.Hour), 0, timestamp); len(got) != 0 {
+		t.Errorf("downsample() after the last sample = %v, want nothing", items(got))
+	}
+}
+
+func TestRate(t *testing.T) {
+	if got := rate(100, 300, 2); got != 100 {
+		t.Errorf("rate() = %v, want 100", got)
+	}
+	if got := rate(300, 100, 2); got != 0 {
+		t.Errorf("rate() after a counter reset = %v, want 0", got)
+	}
+}
+
+func TestHistorySeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHistory(10)
+	for i, s := range []struct {
+		user, idle, read, rx uint64
+	}{
+		{0, 0, 0, 0},
+		{50, 50, 500, 1000},    // half busy
+		{125, 75, 500, 3000},   // three quarters busy
+		{125, 175, 1500, 2000}, // idle, with the network counter reset
+	} {
+		h.Add(Stats{
+			Timestamp:    start.Add(time.Duration(i) * 10 * time.Second),
+			MemStats:     &linux.MemInfo{MemTotal: 1000, MemAvailable: 400},
+			CpuStats:     &linux.CPUStat{User: s.user, Idle: s.idle},
+			CpuCoreStats: []linux.CPUStat{{User: s.user, Idle: s.idle}},
+			DiskIOStats:  &DiskIO{ReadBytes: s.read},
+			// loopback traffic isn't counted
+			NetStats:      []linux.NetworkStat{{Iface: "lo", RxBytes: 1 << 30}, {Iface: "eth0", RxBytes: s.rx}},
+			PressureStats: &Pressure{Cpu: &PressureStat{Some: PressureLine{Avg10: float64(i)}}},
+		})
+	}
+
+	points := h.Series(start, 0)
+	if len(points) != 4 {
+		t.Fatalf("Series() returned %d points, want 4", len(points))
+	}
+	wantCpu := []float64{0, 50, 75, 0}
+	wantRead := []float64{0, 50, 0, 100}
+	wantRx := []float64{0, 100, 200, 0}
+	for i, p := range points {
+		if p.CpuPercent != wantCpu[i] || p.DiskReadBytesPerSec != wantRead[i] || p.NetRxBytesPerSec != wantRx[i] {
+			t.Errorf("point %d: cpu %v%%, read %v/s, rx %v/s, want %v%%, %v/s and %v/s", i,
+				p.CpuPercent, p.DiskReadBytesPerSec, p.NetRxBytesPerSec, wantCpu[i], wantRead[i], wantRx[i])
+		}
+		if i > 0 && !slices.Equal(p.CoreCpuPercent, []float64{wantCpu[i]}) {
+			t.Errorf("point %d: core usage %v, want [%v]", i, p.CoreCpuPercent, wantCpu[i])
+		}
+		if p.MemUsedKb != 600 || p.MemAvailableKb != 400 {
+			t.Errorf("point %d: memory used %d KiB, available %d KiB, want 600 and 400", i, p.MemUsedKb, p.MemAvailableKb)
+		}
+		if p.CpuPressure != float64(i) {
+			t.Errorf("point %d: CPU pressure %v, want %v", i, p.CpuPressure, i)
+		}
+	}
+
+	// the first point of a later range gets its rates from the sample before it
+	points = h.Series(start.Add(15*time.Second), 0)
+	if len(points) != 2 || points[0].CpuPercent != 75 || points[0].NetRxBytesPerSec != 200 {
+		t.Errorf("Series() since 15s = %+v, want 2 points starting at 75%% CPU and 200 B/s received", points)
+	}
+}
+
+func TestTaskHistorySeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := NewTaskHistory(3)
+	if h.Latest() != nil {
+		t.Error("Latest() of an empty history should be nil")
+	}
+	if points := h.Series(start, 0); points == nil || len(points) != 0 {
+		t.Errorf("Series() of an empty history = %v, want an empty series", points)
+	}
+
+	for i := 0; i < 4; i++ {
+		h.Add(TaskStats{
+			Timestamp:   start.Add(time.Duration(i) * 10 * time.Second),
+			CpuPercent:  float64(i),
+			MemoryUsage: 100,
+			MemoryLimit: 400,
+			NetworkRx:   uint64(i) * 1000,
+			BlockWrite:  uint64(i) * 10,
+		})
+	}
+	if latest := h.Latest(); latest == nil || latest.CpuPercent != 3 {
+		t.Fatalf("Latest() = %+v, want the fourth sample", latest)
+	}
+
+	// the first sample fell out of the history, so the first point has no rates
+	points := h.Series(start, 0)
+	if len(points) != 3 {
+		t.Fatalf("Series() returned %d points, want 3", len(points))
+	}
+	if points[0].NetworkRxBytesPerSec != 0 || points[1].NetworkRxBytesPerSec != 100 || points[2].BlockWriteBytesPerSec != 1 {
+		t.Errorf("Series() rates = %+v", points)
+	}
+	if points[2].MemoryPercent != 25 {
+		t.Errorf("MemoryPercent = %v, want 25", points[2].MemoryPercent)
+	}
+
+	points = h.Series(start.Add(25*time.Second), 0)
+	if len(points) != 1 || points[0].NetworkRxBytesPerSec != 100 {
+		t.Errorf("Series() since 25s = %+v, want one point with its rate against the sample before it", points)
+	}
+}
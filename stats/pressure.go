@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Pressure is the pressure stall information of the host: how much of the time tasks were
+// stalled waiting on each resource. It requires Linux 4.20 or later built with CONFIG_PSI.
+// See https://docs.kernel.org/accounting/psi.html
+type Pressure struct {
+	Cpu    *PressureStat
+	Memory *PressureStat
+	Io     *PressureStat
+}
+
+// PressureStat holds the share of time some or all non-idle tasks were stalled on a resource.
+type PressureStat struct {
+	Some PressureLine
+	Full PressureLine
+}
+
+// PressureLine holds the percentage of time stalled averaged over 10, 60 and 300 seconds, and
+// the total stall time in microseconds.
+type PressureLine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// GetPressure reads /proc/pressure, returning nil when the kernel doesn't provide it.
+func GetPressure() *Pressure {
+	cpu, err := ReadPressure("/proc/pressure/cpu")
+	if err != nil {
+		return nil
+	}
+	p := Pressure{Cpu: cpu}
+
+	p.Memory, err = ReadPressure("/proc/pressure/memory")
+	if err != nil {
+		p.Memory = nil
+	}
+	p.Io, err = ReadPressure("/proc/pressure/io")
+	if err != nil {
+		p.Io = nil
+	}
+	return &p
+}
+
+// ReadPressure parses a file of the form
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func ReadPressure(path string) (*PressureStat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p PressureStat
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var line *PressureLine
+		switch fields[0] {
+		case "some":
+			line = &p.Some
+		case "full":
+			line = &p.Full
+		default:
+			return nil, fmt.Errorf("unexpected line in %s: %s", path, scanner.Text())
+		}
+
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("unexpected field in %s: %s", path, field)
+			}
+			switch key {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse %s in %s: %v", field, path, err)
+			}
+		}
+	}
+	return &p, scanner.Err()
+}
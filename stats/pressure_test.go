@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePressureFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "memory")
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPressure(t *testing.T) {
+	path := writePressureFile(t, "some avg10=1.50 avg60=0.75 avg300=0.10 total=12345\nfull avg10=0.50 avg60=0.25 avg300=0.00 total=678\n")
+
+	p, err := ReadPressure(path)
+	if err != nil {
+		t.Fatalf("ReadPressure() error: %v", err)
+	}
+	want := PressureStat{
+		Some: PressureLine{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 12345},
+		Full: PressureLine{Avg10: 0.5, Avg60: 0.25, Total: 678},
+	}
+	if *p != want {
+		t.Errorf("ReadPressure() = %+v, want %+v", *p, want)
+	}
+}
+
+func TestReadPressureWithoutFullLine(t *testing.T) {
+	// older kernels have no full line for cpu
+	p, err := ReadPressure(writePressureFile(t, "\nsome avg10=2.00 avg60=1.00 avg300=0.50 total=42\n\n"))
+	if err != nil {
+		t.Fatalf("ReadPressure() error: %v", err)
+	}
+	if p.Some.Avg10 != 2 || p.Some.Total != 42 || p.Full != (PressureLine{}) {
+		t.Errorf("ReadPressure() = %+v", *p)
+	}
+}
+
+func TestReadPressureErrors(t *testing.T) {
+	for _, content := range []string{
+		"half avg10=0.00\n",
+		"some avg10\n",
+		"some avg10=abc\n",
+		"some total=-1\n",
+	} {
+		if p, err := ReadPressure(writePressureFile(t, content)); err == nil {
+			t.Errorf("ReadPressure(%q) = %+v, want an error", content, p)
+		}
+	}
+	if _, err := ReadPressure(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ReadPressure() of a missing file should fail")
+	}
+}
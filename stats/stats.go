@@ -2,16 +2,31 @@ package stats
 
 import (
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/c9s/goprocinfo/linux"
 )
 
 type Stats struct {
-	MemStats  *linux.MemInfo
-	DiskStats *linux.Disk
-	CpuStats  *linux.CPUStat
-	LoadStats *linux.LoadAvg
-	TaskCount int
+	MemStats      *linux.MemInfo
+	DiskStats     *linux.Disk
+	DiskIOStats   *DiskIO
+	MountStats    map[string]*linux.Disk // [path]usage of the filesystem holding it
+	CpuStats      *linux.CPUStat
+	CpuCoreStats  []linux.CPUStat
+	NetStats      []linux.NetworkStat
+	LoadStats     *linux.LoadAvg
+	PressureStats *Pressure
+	TaskCount     int
+	Timestamp     time.Time
+}
+
+// DiskIO is the number of bytes read from and written to all block devices since boot.
+type DiskIO struct {
+	ReadBytes  uint64
+	WriteBytes uint64
 }
 
 func (s *Stats) MemUsedKb() uint64 {
@@ -42,6 +57,23 @@ func (s *Stats) DiskUsed() uint64 {
 	return s.DiskStats.Used
 }
 
+// Cores returns the number of CPU cores, or 0 if per-core stats were not collected.
+func (s *Stats) Cores() int {
+	return len(s.CpuCoreStats)
+}
+
+// NetBytes returns the bytes received and sent on all interfaces except loopback since boot.
+func (s *Stats) NetBytes() (rx uint64, tx uint64) {
+	for _, n := range s.NetStats {
+		if n.Iface == "lo" {
+			continue
+		}
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
 func (s *Stats) CpuUsage() float64 {
 
 	idle := s.CpuStats.Idle + s.CpuStats.IOWait
@@ -55,12 +87,58 @@ func (s *Stats) CpuUsage() float64 {
 	return (float64(total) - float64(idle)) / float64(total)
 }
 
-func GetStats() *Stats {
+// CpuUsageBetween returns the fraction of CPU time spent non-idle between two samples,
+// which unlike CpuUsage reflects the current load rather than the average since boot.
+// See https://stackoverflow.com/questions/23367857/accurate-calculation-of-cpu-usage-given-in-percentage-in-linux
+func CpuUsageBetween(prev *Stats, cur *Stats) float64 {
+	prevIdle, prevTotal := cpuTimes(prev.CpuStats)
+	curIdle, curTotal := cpuTimes(cur.CpuStats)
+	if curTotal <= prevTotal || curIdle < prevIdle {
+		return 0.00
+	}
+
+	total := curTotal - prevTotal
+	idle := curIdle - prevIdle
+	return (float64(total) - float64(idle)) / float64(total)
+}
+
+// CoreUsageBetween returns the usage of each CPU core between two samples, like CpuUsageBetween.
+// It returns nil when the samples don't list the same cores, e.g. after a CPU was hotplugged.
+func CoreUsageBetween(prev *Stats, cur *Stats) []float64 {
+	if len(prev.CpuCoreStats) != len(cur.CpuCoreStats) {
+		return nil
+	}
+	usage := make([]float64, len(cur.CpuCoreStats))
+	for i := range cur.CpuCoreStats {
+		usage[i] = CpuUsageBetween(&Stats{CpuStats: &prev.CpuCoreStats[i]}, &Stats{CpuStats: &cur.CpuCoreStats[i]})
+	}
+	return usage
+}
+
+func cpuTimes(c *linux.CPUStat) (idle uint64, total uint64) {
+	if c == nil {
+		return 0, 0
+	}
+	idle = c.Idle + c.IOWait
+	nonIdle := c.User + c.Nice + c.System + c.IRQ + c.SoftIRQ + c.Steal
+	return idle, idle + nonIdle
+}
+
+// GetStats samples the host. Besides the root filesystem, the usage of the filesystems holding
+// each of mountPaths, such as the Docker data root, is reported in MountStats.
+func GetStats(mountPaths ...string) *Stats {
+	cpuStats, cpuCoreStats := GetCpuStats()
 	return &Stats{
-		MemStats:  GetMemoryInfo(),
-		DiskStats: GetDiskInfo(),
-		CpuStats:  GetCpuStats(),
-		LoadStats: GetLoadAvg(),
+		MemStats:      GetMemoryInfo(),
+		DiskStats:     GetDiskInfo(),
+		DiskIOStats:   GetDiskIO(),
+		MountStats:    GetMountStats(mountPaths),
+		CpuStats:      cpuStats,
+		CpuCoreStats:  cpuCoreStats,
+		NetStats:      GetNetStats(),
+		LoadStats:     GetLoadAvg(),
+		PressureStats: GetPressure(),
+		Timestamp:     time.Now().UTC(),
 	}
 }
 
@@ -86,15 +164,65 @@ func GetDiskInfo() *linux.Disk {
 	return diskstats
 }
 
-// GetCpuInfo See https://godoc.org/github.com/c9s/goprocinfo/linux#CPUStat
-func GetCpuStats() *linux.CPUStat {
+// GetMountStats See https://godoc.org/github.com/c9s/goprocinfo/linux#Disk
+func GetMountStats(paths []string) map[string]*linux.Disk {
+	mounts := make(map[string]*linux.Disk)
+	for _, path := range paths {
+		diskstats, err := linux.ReadDisk(path)
+		if err != nil {
+			log.Printf("Error reading from %s", path)
+			continue
+		}
+		mounts[path] = diskstats
+	}
+	return mounts
+}
+
+// GetDiskIO sums the sectors read and written by the whole disks listed in /proc/diskstats.
+// Partitions, which would be counted twice, have no entry of their own in /sys/block.
+// See https://www.kernel.org/doc/Documentation/iostats.txt
+func GetDiskIO() *DiskIO {
+	diskstats, err := linux.ReadDiskStats("/proc/diskstats")
+	if err != nil {
+		log.Printf("Error reading from /proc/diskstats")
+		return &DiskIO{}
+	}
+
+	var io DiskIO
+	for _, d := range diskstats {
+		if strings.HasPrefix(d.Name, "loop") || strings.HasPrefix(d.Name, "ram") {
+			continue
+		}
+		if _, err := os.Stat("/sys/block/" + d.Name); err != nil {
+			continue
+		}
+		io.ReadBytes += d.ReadSectors * 512
+		io.WriteBytes += d.WriteSectors * 512
+	}
+	return &io
+}
+
+// GetCpuStats returns the aggregate and the per-core CPU times.
+// See https://godoc.org/github.com/c9s/goprocinfo/linux#CPUStat
+func GetCpuStats() (*linux.CPUStat, []linux.CPUStat) {
 	stats, err := linux.ReadStat("/proc/stat")
 	if err != nil {
 		log.Printf("Error reading from /proc/stat")
-		return &linux.CPUStat{}
+		return &linux.CPUStat{}, nil
+	}
+
+	return &stats.CPUStatAll, stats.CPUStats
+}
+
+// GetNetStats See https://godoc.org/github.com/c9s/goprocinfo/linux#NetworkStat
+func GetNetStats() []linux.NetworkStat {
+	netstats, err := linux.ReadNetworkStat("/proc/net/dev")
+	if err != nil {
+		log.Printf("Error reading from /proc/net/dev")
+		return nil
 	}
 
-	return &stats.CPUStatAll
+	return netstats
 }
 
 // GetLoadAvg See https://godoc.org/github.com/c9s/goprocinfo/linux#LoadAvg
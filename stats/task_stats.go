@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/google/uuid"
+)
+
+// TaskStats is the resource usage of a single task's container.
+type TaskStats struct {
+	TaskID      uuid.UUID
+	TaskName    string
+	ContainerID string
+	Timestamp   time.Time
+	CpuPercent  float64 // like `docker stats`, 100% is one fully used core
+	MemoryUsage uint64  // bytes, excluding the page cache
+	MemoryLimit uint64  // bytes
+	NetworkRx   uint64  // bytes received on all interfaces since the container started
+	NetworkTx   uint64  // bytes sent on all interfaces since the container started
+	BlockRead   uint64  // bytes read from block devices since the container started
+	BlockWrite  uint64  // bytes written to block devices since the container started
+}
+
+// MemoryPercent returns the memory usage as a percentage of the container's limit.
+func (s *TaskStats) MemoryPercent() float64 {
+	if s.MemoryLimit == 0 {
+		return 0.00
+	}
+	return float64(s.MemoryUsage) / float64(s.MemoryLimit) * 100
+}
+
+// NewTaskStats converts the stats reported by the Docker API, following the calculations of the docker CLI.
+// See https://docs.docker.com/engine/api/v1.45/#tag/Container/operation/ContainerStats
+func NewTaskStats(taskID uuid.UUID, taskName string, s *container.StatsResponse) *TaskStats {
+	ts := TaskStats{
+		TaskID:      taskID,
+		TaskName:    taskName,
+		ContainerID: s.ID,
+		Timestamp:   s.Read,
+		CpuPercent:  containerCpuPercent(s),
+		MemoryUsage: containerMemoryUsage(s),
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+
+	for _, n := range s.Networks {
+		ts.NetworkRx += n.RxBytes
+		ts.NetworkTx += n.TxBytes
+	}
+
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			ts.BlockRead += e.Value
+		case "write":
+			ts.BlockWrite += e.Value
+		}
+	}
+	return &ts
+}
+
+func containerCpuPercent(s *container.StatsResponse) float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0.00
+	}
+
+	onlineCpus := float64(s.CPUStats.OnlineCPUs)
+	if onlineCpus == 0 {
+		onlineCpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCpus * 100
+}
+
+// containerMemoryUsage subtracts the inactive page cache, which the kernel can reclaim, from the usage.
+// cgroup v1 reports it as total_inactive_file and cgroup v2 as inactive_file.
+func containerMemoryUsage(s *container.StatsResponse) uint64 {
+	usage := s.MemoryStats.Usage
+	cache, ok := s.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = s.MemoryStats.Stats["inactive_file"]
+	}
+	if cache < usage {
+		return usage - cache
+	}
+	return usage
+}
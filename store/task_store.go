@@ -7,6 +7,7 @@ import (
 	"github.com/boltdb/bolt"
 	"log"
 	"os"
+	"sync"
 )
 
 type TaskStore struct {
@@ -16,8 +17,11 @@ type TaskStore struct {
 	Bucket   string
 }
 
+// InMemoryTaskStore is safe for concurrent use. Like TaskStore it stores and returns copies of
+// the tasks, so a task must be put back for changes to be stored.
 type InMemoryTaskStore struct {
 	Db map[string]*task.Task
+	mu sync.RWMutex
 }
 
 func NewInMemoryTaskStore() *InMemoryTaskStore {
@@ -31,28 +35,39 @@ func (i *InMemoryTaskStore) Put(key string, value interface{}) error {
 	if !ok {
 		return fmt.Errorf("value %v is not a task.Task type", value)
 	}
-	i.Db[key] = t
+	c := *t
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.Db[key] = &c
 	return nil
 }
 
 func (i *InMemoryTaskStore) Get(key string) (interface{}, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	t, ok := i.Db[key]
 	if !ok {
 		return nil, fmt.Errorf("task with key %s does not exist", key)
 	}
 
-	return t, nil
+	c := *t
+	return &c, nil
 }
 
 func (i *InMemoryTaskStore) List() (interface{}, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	var tasks []*task.Task
 	for _, t := range i.Db {
-		tasks = append(tasks, t)
+		c := *t
+		tasks = append(tasks, &c)
 	}
 	return tasks, nil
 }
 
 func (i *InMemoryTaskStore) Count() (int, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return len(i.Db), nil
 }
 
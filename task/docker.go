@@ -1,9 +1,13 @@
 package task
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
@@ -14,15 +18,13 @@ import (
 	"os"
 )
 
-const MaxRestartPolicy = 5
-
-var RestartPolicyMap = map[string]container.RestartPolicyMode{
-	"":               container.RestartPolicyDisabled,
-	"no":             container.RestartPolicyDisabled,
-	"always":         container.RestartPolicyAlways,
-	"unless-stopped": container.RestartPolicyUnlessStopped,
-	"on-failure":     container.RestartPolicyOnFailure,
-}
+// Labels set on the Docker objects the workers create for tasks, see Task.Labels.
+const (
+	LabelWorker   = "cube.worker" // name of the worker that created the object
+	LabelTaskID   = "cube.task.id"
+	LabelTaskName = "cube.task.name"
+	LabelJob      = "cube.job" // only set for tasks that belong to a job
+)
 
 // Docker is a struct that encapsulates everything we need to run our task as a Docker container
 type Docker struct {
@@ -33,7 +35,7 @@ type Docker struct {
 func NewDocker(c *Config) (*Docker, error) {
 	dc, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
-		slog.Error("Error creating Docker client: %v\n", err)
+		slog.Error("Error creating Docker client", "error", err)
 		return nil, err
 	}
 	return &Docker{
@@ -55,6 +57,17 @@ type DockerInspectResponse struct {
 	Container *types.ContainerJSON
 }
 
+type DockerStatsResponse struct {
+	Error error
+	Stats *container.StatsResponse
+}
+
+type DockerExecResponse struct {
+	Error    error
+	ExitCode int
+	Output   string // combined stdout and stderr
+}
+
 func (d *Docker) Run() DockerResult {
 	ctx := context.Background()
 	reader, err := d.Client.ImagePull(
@@ -65,13 +78,9 @@ func (d *Docker) Run() DockerResult {
 	}
 	io.Copy(os.Stdout, reader)
 
-	maximumRetryCount := 0
-	if d.Config.RestartPolicy == string(container.RestartPolicyOnFailure) {
-		maximumRetryCount = MaxRestartPolicy
-	}
+	// the worker restarts tasks according to their restart policy, so Docker must not
 	rp := container.RestartPolicy{
-		Name:              RestartPolicyMap[d.Config.RestartPolicy],
-		MaximumRetryCount: maximumRetryCount,
+		Name: container.RestartPolicyDisabled,
 	}
 
 	r := container.Resources{
@@ -80,9 +89,10 @@ func (d *Docker) Run() DockerResult {
 	}
 
 	cc := container.Config{
-		Image: d.Config.Image,
-		Tty:   false,
-		Env:   d.Config.Env,
+		Image:  d.Config.Image,
+		Tty:    false,
+		Env:    d.Config.Env,
+		Labels: d.Config.Labels,
 		// TODO figure out why there is a difference between the two
 		ExposedPorts: d.Config.ExposedPorts,
 	}
@@ -121,7 +131,11 @@ func (d *Docker) Run() DockerResult {
 func (d *Docker) Stop(id string) DockerResult {
 	log.Printf("Attempting to stop container %v", id)
 	ctx := context.Background()
-	err := d.Client.ContainerStop(ctx, id, container.StopOptions{})
+	opts := container.StopOptions{}
+	if d.Config.GracePeriod > 0 {
+		opts.Timeout = &d.Config.GracePeriod
+	}
+	err := d.Client.ContainerStop(ctx, id, opts)
 	if err != nil {
 		slog.Error("Error stopping container %s: %v\n", id, err)
 		return DockerResult{Error: err}
@@ -151,3 +165,114 @@ func (d *Docker) Inspect(containerID string) DockerInspectResponse {
 
 	return DockerInspectResponse{Container: &resp}
 }
+
+// Stats returns a single sample of the container's resource usage. Docker waits for a second
+// sample before answering so that the CPU usage can be computed from the difference.
+func (d *Docker) Stats(containerID string) DockerStatsResponse {
+	ctx := context.Background()
+	resp, err := d.Client.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		log.Printf("Error getting stats for container %s: %v\n", containerID, err)
+		return DockerStatsResponse{Error: err}
+	}
+	defer resp.Body.Close()
+
+	var s container.StatsResponse
+	err = json.NewDecoder(resp.Body).Decode(&s)
+	if err != nil {
+		log.Printf("Error decoding stats for container %s: %v\n", containerID, err)
+		return DockerStatsResponse{Error: err}
+	}
+
+	return DockerStatsResponse{Stats: &s}
+}
+
+// Exec runs a command inside a running container and waits for it to exit, or for ctx to be done.
+func (d *Docker) Exec(ctx context.Context, containerID string, cmd []string) DockerExecResponse {
+	exec, err := d.Client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return DockerExecResponse{Error: err}
+	}
+
+	attach, err := d.Client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return DockerExecResponse{Error: err}
+	}
+	defer attach.Close()
+
+	var output bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(&output, &output, attach.Reader)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			return DockerExecResponse{Error: err}
+		}
+	case <-ctx.Done():
+		return DockerExecResponse{Error: ctx.Err()}
+	}
+
+	inspect, err := d.Client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return DockerExecResponse{Error: err}
+	}
+	return DockerExecResponse{ExitCode: inspect.ExitCode, Output: output.String()}
+}
+
+// Events subscribes to the die, oom and health_status events of all containers. The stream ends
+// with an error on the returned error channel, e.g. when the Docker daemon restarts or ctx is done.
+func (d *Docker) Events(ctx context.Context) (<-chan events.Message, <-chan error) {
+	f := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("event", string(events.ActionDie)),
+		filters.Arg("event", string(events.ActionOOM)),
+		filters.Arg("event", string(events.ActionHealthStatus)),
+	)
+	return d.Client.Events(ctx, events.ListOptions{Filters: f})
+}
+
+// List returns the containers, running or not, that carry all the given labels.
+func (d *Docker) List(labels map[string]string) ([]types.Container, error) {
+	return d.Client.ContainerList(context.Background(), container.ListOptions{All: true, Filters: labelFilters(labels)})
+}
+
+// RemoveLabeled removes the containers, networks and volumes that carry all the given labels.
+// Running containers are killed; networks and volumes still used by other containers are kept.
+func (d *Docker) RemoveLabeled(labels map[string]string) error {
+	ctx := context.Background()
+	containers, err := d.List(labels)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		err = d.Client.ContainerRemove(ctx, c.ID, container.RemoveOptions{RemoveVolumes: true, Force: true})
+		if err != nil {
+			return err
+		}
+	}
+
+	f := labelFilters(labels)
+	_, err = d.Client.NetworksPrune(ctx, f)
+	if err != nil {
+		return err
+	}
+	// without all, only anonymous volumes are pruned
+	f.Add("all", "true")
+	_, err = d.Client.VolumesPrune(ctx, f)
+	return err
+}
+
+func labelFilters(labels map[string]string) filters.Args {
+	f := filters.NewArgs()
+	for k, v := range labels {
+		f.Add("label", k+"="+v)
+	}
+	return f
+}
@@ -0,0 +1,99 @@
+package task
+
+import "time"
+
+const (
+	DefaultProbePeriod           = 10 // seconds
+	DefaultProbeTimeout          = 1  // seconds
+	DefaultProbeSuccessThreshold = 1
+	DefaultProbeFailureThreshold = 3
+)
+
+// Probe is a periodic check the worker runs against a task's container. Exactly one of HTTPGet,
+// TCPSocket or Exec should be set. Zero values of the timing fields use the defaults above.
+// See https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle/#container-probes
+type Probe struct {
+	HTTPGet   *HTTPGetAction
+	TCPSocket *TCPSocketAction
+	Exec      *ExecAction
+
+	InitialDelaySeconds int // seconds after the container started before the first check
+	PeriodSeconds       int
+	TimeoutSeconds      int
+	SuccessThreshold    int // consecutive successes after a failure for the probe to pass
+	FailureThreshold    int // consecutive failures for the probe to fail
+}
+
+// HTTPGetAction passes when a GET of Path on the container's published Port returns a status
+// in the 200-399 range. A zero Port uses the first published port.
+type HTTPGetAction struct {
+	Path string
+	Port int
+}
+
+// TCPSocketAction passes when a connection to the container's published Port can be opened.
+type TCPSocketAction struct {
+	Port int
+}
+
+// ExecAction passes when Command, run inside the container, exits with status 0.
+type ExecAction struct {
+	Command []string
+}
+
+func (p *Probe) Period() time.Duration {
+	return secondsOr(p.PeriodSeconds, DefaultProbePeriod)
+}
+
+func (p *Probe) Timeout() time.Duration {
+	return secondsOr(p.TimeoutSeconds, DefaultProbeTimeout)
+}
+
+func (p *Probe) InitialDelay() time.Duration {
+	return time.Duration(p.InitialDelaySeconds) * time.Second
+}
+
+func (p *Probe) Successes() int {
+	if p.SuccessThreshold > 0 {
+		return p.SuccessThreshold
+	}
+	return DefaultProbeSuccessThreshold
+}
+
+func (p *Probe) Failures() int {
+	if p.FailureThreshold > 0 {
+		return p.FailureThreshold
+	}
+	return DefaultProbeFailureThreshold
+}
+
+func secondsOr(seconds int, def int) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(def) * time.Second
+}
+
+type ProbeResult string
+
+const (
+	ProbeUnknown ProbeResult = "Unknown"
+	ProbeSuccess ProbeResult = "Success"
+	ProbeFailure ProbeResult = "Failure"
+)
+
+// ProbeStatus is the outcome of a task's probe as last observed by its worker.
+type ProbeStatus struct {
+	Result               ProbeResult
+	ConsecutiveSuccesses int
+	ConsecutiveFailures  int
+	LastProbeTime        time.Time
+	Message              string // why the last check failed
+}
+
+// ProbeStatuses holds the status of each probe configured on a task.
+type ProbeStatuses struct {
+	Startup   *ProbeStatus
+	Liveness  *ProbeStatus
+	Readiness *ProbeStatus
+}
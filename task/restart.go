@@ -0,0 +1,81 @@
+package task
+
+import (
+	"strings"
+	"time"
+)
+
+type RestartPolicy string
+
+const (
+	// RestartAlways restarts the task's container whenever it exits.
+	RestartAlways RestartPolicy = "Always"
+	// RestartOnFailure restarts the container when it exits with a non-zero status or fails
+	// its liveness probe. It is the default.
+	RestartOnFailure RestartPolicy = "OnFailure"
+	// RestartNever lets the task complete or fail with its container.
+	RestartNever RestartPolicy = "Never"
+)
+
+const (
+	// DefaultMaxRestarts is used for tasks that don't set MaxRestarts.
+	DefaultMaxRestarts = 3
+	// DefaultRestartBackoff is the delay before the first restart. It doubles with every restart.
+	DefaultRestartBackoff = 10 * time.Second
+	// DefaultMaxRestartBackoff caps the delay between restarts.
+	DefaultMaxRestartBackoff = 5 * time.Minute
+)
+
+// restartPolicyAliases accepts the names of Docker's restart policies, which tasks used before
+// restarts were handled by the worker.
+var restartPolicyAliases = map[string]RestartPolicy{
+	"":           RestartOnFailure,
+	"always":     RestartAlways,
+	"on-failure": RestartOnFailure,
+	"onfailure":  RestartOnFailure,
+	"never":      RestartNever,
+	"no":         RestartNever,
+}
+
+// Restart returns the task's restart policy. Unknown values fall back to OnFailure.
+func (t Task) Restart() RestartPolicy {
+	if p, ok := restartPolicyAliases[strings.ToLower(t.RestartPolicy)]; ok {
+		return p
+	}
+	return RestartOnFailure
+}
+
+// ShouldRestart reports whether the task's container should be restarted after it exited,
+// taking the restart policy and the restarts already made into account. failed is true when the
+// container exited with a non-zero status or was stopped by a failed probe.
+func (t Task) ShouldRestart(failed bool) bool {
+	switch t.Restart() {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		if !failed {
+			return false
+		}
+	}
+	return t.MaxRestarts < 0 || t.RestartCount < t.maxRestarts()
+}
+
+func (t Task) maxRestarts() int {
+	if t.MaxRestarts == 0 {
+		return DefaultMaxRestarts
+	}
+	return t.MaxRestarts
+}
+
+// RestartBackoff returns how long to wait before the next restart: initial doubled for each
+// restart already made, capped at max.
+func (t Task) RestartBackoff(initial time.Duration, max time.Duration) time.Duration {
+	delay := initial
+	for i := 0; i < t.RestartCount && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyAliases(t *testing.T) {
+	for policy, want := range map[string]RestartPolicy{
+		"":           RestartOnFailure,
+		"Always":     RestartAlways,
+		"always":     RestartAlways,
+		"on-failure": RestartOnFailure,
+		"OnFailure":  RestartOnFailure,
+		"Never":      RestartNever,
+		"no":         RestartNever,
+		"sometimes":  RestartOnFailure,
+	} {
+		if got := (Task{RestartPolicy: policy}).Restart(); got != want {
+			t.Errorf("Restart() of %q = %s, want %s", policy, got, want)
+		}
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	always := Task{RestartPolicy: "Always"}
+	if !always.ShouldRestart(false) || !always.ShouldRestart(true) {
+		t.Error("Always should restart whatever the exit status")
+	}
+
+	onFailure := Task{RestartPolicy: "OnFailure"}
+	if onFailure.ShouldRestart(false) {
+		t.Error("OnFailure should not restart a container that completed")
+	}
+	if !onFailure.ShouldRestart(true) {
+		t.Error("OnFailure should restart a container that failed")
+	}
+
+	never := Task{RestartPolicy: "Never"}
+	if never.ShouldRestart(true) {
+		t.Error("Never should not restart a container that failed")
+	}
+}
+
+func TestShouldRestartStopsAtMaxRestarts(t *testing.T) {
+	tk := Task{RestartPolicy: "Always", RestartCount: DefaultMaxRestarts - 1}
+	if !tk.ShouldRestart(true) {
+		t.Error("should restart below the default maximum")
+	}
+	tk.RestartCount = DefaultMaxRestarts
+	if tk.ShouldRestart(true) {
+		t.Error("should not restart once the default maximum is reached")
+	}
+
+	tk.MaxRestarts = 5
+	if !tk.ShouldRestart(true) {
+		t.Error("should restart below a custom maximum")
+	}
+	tk.RestartCount = 5
+	if tk.ShouldRestart(true) {
+		t.Error("should not restart once the custom maximum is reached")
+	}
+
+	tk.MaxRestarts = -1
+	tk.RestartCount = 1000
+	if !tk.ShouldRestart(true) {
+		t.Error("a negative maximum should allow any number of restarts")
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute}
+	for count, delay := range want {
+		tk := Task{RestartCount: count}
+		if got := tk.RestartBackoff(10*time.Second, 5*time.Minute); got != delay {
+			t.Errorf("RestartBackoff() after %d restarts = %v, want %v", count, got, delay)
+		}
+	}
+	if got := (Task{RestartCount: 1000}).RestartBackoff(time.Second, time.Minute); got != time.Minute {
+		t.Errorf("RestartBackoff() after many restarts = %v, want the maximum", got)
+	}
+}
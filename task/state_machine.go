@@ -3,19 +3,26 @@ package task
 type State int
 
 const (
-	Pending   State = iota // The initial state, the starting point, for every task.
-	Scheduled              // Once the manager has scheduled it onto a worker.
-	Running                // When a worker successfully starts the task (i.e., starts the container).
-	Completed              // When a task completes its work in a normal way (i.e., it does not fail).
-	Failed                 // If a task fails, it moves to this state.
+	Pending          State = iota // The initial state, the starting point, for every task.
+	Scheduled                     // Once the manager has scheduled it onto a worker.
+	Running                       // When a worker successfully starts the task (i.e., starts the container).
+	Completed                     // When a task completes its work in a normal way (i.e., it does not fail).
+	Failed                        // If a task fails, it moves to this state.
+	Lost                          // When the node running the task stopped responding; the task is waiting to be rescheduled.
+	Evicted                       // When the worker stopped the task to reclaim resources; the task is waiting to be rescheduled.
+	CrashLoopBackOff              // When the task's container exited and the worker is waiting to restart it.
 )
 
 var stateTransitionMap = map[State][]State{
 	Pending:   []State{Scheduled},
-	Scheduled: []State{Scheduled, Running, Failed},
-	Running:   []State{Running, Completed, Failed},
+	Scheduled: []State{Scheduled, Running, Failed, Lost, Evicted},
+	Running:   []State{Running, Completed, Failed, Lost, Evicted, CrashLoopBackOff},
 	Completed: []State{},
 	Failed:    []State{},
+	Lost:      []State{Scheduled},
+	Evicted:   []State{Scheduled},
+	// a task waiting to be restarted can still be stopped, lost or evicted
+	CrashLoopBackOff: []State{Running, Completed, Failed, Lost, Evicted},
 }
 
 func Contains(states []State, state State) bool {
@@ -30,3 +37,26 @@ func Contains(states []State, state State) bool {
 func ValidStateTransition(from State, to State) bool {
 	return Contains(stateTransitionMap[from], to)
 }
+
+// Terminal reports whether a task in this state is done for good, i.e. it can't leave the state.
+func (s State) Terminal() bool {
+	return len(stateTransitionMap[s]) == 0
+}
+
+var stateNames = map[State]string{
+	Pending:          "Pending",
+	Scheduled:        "Scheduled",
+	Running:          "Running",
+	Completed:        "Completed",
+	Failed:           "Failed",
+	Lost:             "Lost",
+	Evicted:          "Evicted",
+	CrashLoopBackOff: "CrashLoopBackOff",
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
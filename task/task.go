@@ -19,12 +19,52 @@ type Task struct {
 	ExposedPorts  nat.PortSet
 	HostPorts     nat.PortMap
 	PortBindings  map[string]string
-	RestartPolicy string
+	RestartPolicy string // "Always", "OnFailure" (default) or "Never", enforced by the worker
 	ContainerID   string
 	StartTime     time.Time
 	FinishTime    time.Time
-	HealthCheck   string
+	HealthCheck   string // path of an HTTP liveness check, used when LivenessProbe is not set
 	RestartCount  int
+	// MaxRestarts is how many times the worker restarts the task's container before failing
+	// the task. Zero uses DefaultMaxRestarts and a negative value means no limit.
+	MaxRestarts int
+	// ExitCode and ExitReason describe how the task's container last exited.
+	ExitCode   int
+	ExitReason string
+	// ResourceVersion is increased by the worker every time it stores the task, so that the
+	// manager can tell which of two reports about the task is the latest.
+	ResourceVersion uint64
+	// RestartAt is when the worker restarts a task waiting in CrashLoopBackOff.
+	RestartAt     time.Time
+	Tolerations   []Toleration
+	PriorityClass string
+	// Job groups tasks that belong together. When GangSize is greater than one, none of the job's
+	// tasks are started until GangSize of them can be placed at the same time.
+	Job      string
+	GangSize int
+	// Service is the name of the replicated service the task is a replica of, if any.
+	Service string
+	// GracePeriod is how many seconds the container is given to exit after SIGTERM before it is killed.
+	// Zero uses Docker's default.
+	GracePeriod int
+	// StartupProbe holds off the other probes until it passes, for slow starting containers.
+	// The container is stopped and the task fails when the startup or liveness probe fails.
+	StartupProbe   *Probe
+	LivenessProbe  *Probe
+	ReadinessProbe *Probe
+	// Ready is set by the worker while the task passes its readiness probe, or while it is
+	// running and started if it has none.
+	Ready  bool
+	Probes ProbeStatuses
+}
+
+// Toleration allows a task to be scheduled on (or keep running on) a node with a matching taint.
+// An empty Key with the Exists operator tolerates every taint, and an empty Effect matches all effects.
+type Toleration struct {
+	Key      string
+	Operator string // "Equal" (default) or "Exists"
+	Value    string
+	Effect   string
 }
 
 // Event (TaskEvent) an internal object that our system uses to trigger tasks from one state to another.
@@ -49,6 +89,8 @@ type Config struct {
 	Disk          uint64 // Disk in GiB
 	Env           []string
 	RestartPolicy string
+	GracePeriod   int // seconds to wait for the container to stop before killing it
+	Labels        map[string]string
 }
 
 // NewConfig creates a new Config object from a Task object.
@@ -66,5 +108,44 @@ func NewConfig(t *Task) *Config {
 		Disk:          t.Disk,
 		Env:           []string{},
 		RestartPolicy: t.RestartPolicy,
+		GracePeriod:   t.GracePeriod,
+	}
+}
+
+// PriorityClassMap maps the priority class names a task can declare to their numeric priority.
+// Tasks with a higher priority are scheduled first and may preempt lower-priority tasks.
+var PriorityClassMap = map[string]int{
+	"":         0,
+	"batch":    -100,
+	"low":      -10,
+	"normal":   0,
+	"high":     100,
+	"critical": 1000,
+}
+
+// Priority returns the numeric priority of the task's priority class; unknown classes get the default priority.
+func (t Task) Priority() int {
+	return PriorityClassMap[t.PriorityClass]
+}
+
+// Labels returns the labels that tie the Docker objects created for the task to the task and to
+// the worker that created them.
+func (t Task) Labels(worker string) map[string]string {
+	labels := map[string]string{
+		LabelWorker:   worker,
+		LabelTaskID:   t.ID.String(),
+		LabelTaskName: t.Name,
+	}
+	if t.Job != "" {
+		labels[LabelJob] = t.Job
+	}
+	return labels
+}
+
+// Liveness returns the task's liveness probe, derived from HealthCheck when LivenessProbe is not set.
+func (t Task) Liveness() *Probe {
+	if t.LivenessProbe != nil || t.HealthCheck == "" {
+		return t.LivenessProbe
 	}
+	return &Probe{HTTPGet: &HTTPGetAction{Path: t.HealthCheck}}
 }
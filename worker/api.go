@@ -20,11 +20,16 @@ type Api struct {
 
 func (a *Api) initRouter() {
 	a.Router = chi.NewRouter()
+	a.Router.Use(httpMetrics.Middleware)
+	registry.OnCollect(a.Worker.updateMetrics)
+	a.Router.Get("/metrics", registry.Handler())
 	a.Router.Route("/tasks", func(r chi.Router) {
 		r.Post("/", a.StartTaskHandler)
 		r.Get("/", a.GetTasksHandler)
+		r.Get("/stats", a.GetTasksStatsHandler)
 		r.Route("/{taskID}", func(r chi.Router) {
 			r.Delete("/", a.StopTaskHandler)
+			r.Get("/stats", a.GetTaskStatsHandler)
 		})
 	})
 	a.Router.Route("/stats", func(r chi.Router) {
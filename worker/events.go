@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/docker/docker/api/types/events"
+)
+
+// eventRetryInterval is how long WatchContainers waits before resubscribing to a broken event stream.
+const eventRetryInterval = 5 * time.Second
+
+// WatchContainers updates the tasks as soon as Docker reports that their container exited, ran
+// out of memory or changed health. When the event stream breaks, e.g. because the Docker daemon
+// restarted, it resubscribes and resyncs the tasks with updateTasks in case events were missed.
+func (w *Worker) WatchContainers() {
+	for {
+		err := w.watchContainers()
+		log.Printf("[worker] container event stream ended: %v, resubscribing in %v\n", err, eventRetryInterval)
+		time.Sleep(eventRetryInterval)
+		w.updateTasks()
+	}
+}
+
+func (w *Worker) watchContainers() error {
+	d, err := task.NewDocker(&task.Config{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages, errs := d.Events(ctx)
+	for {
+		select {
+		case m := <-messages:
+			w.handleContainerEvent(m)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// handleContainerEvent records the event in the oomKilled and unhealthy maps right away, in the
+// order the events arrive, and applies it to the task on the task's lane.
+func (w *Worker) handleContainerEvent(m events.Message) {
+	id := m.Actor.ID
+	t := w.taskForContainer(id)
+	if t == nil {
+		// not the current container of one of our tasks
+		return
+	}
+	taskID := t.ID.String()
+
+	switch {
+	case m.Action == events.ActionOOM:
+		// the die event follows
+		w.eventsMu.Lock()
+		w.oomKilled[id] = true
+		w.eventsMu.Unlock()
+
+	case m.Action == events.ActionDie:
+		w.eventsMu.Lock()
+		oom := w.oomKilled[id]
+		delete(w.oomKilled, id)
+		delete(w.unhealthy, id)
+		w.eventsMu.Unlock()
+
+		exitCode, _ := strconv.Atoi(m.Actor.Attributes["exitCode"])
+		reason := ExitCompleted
+		switch {
+		case oom:
+			reason = ExitOOMKilled
+		case exitCode != 0:
+			reason = ExitError
+		}
+		w.dispatch(taskID, func() {
+			// containers stopped by the worker itself belong to tasks that already left Running
+			t := w.runningTask(taskID, id)
+			if t == nil {
+				return
+			}
+			w.containerExited(t, exitCode, reason)
+		})
+
+	case strings.HasPrefix(string(m.Action), string(events.ActionHealthStatus)):
+		// the result of the HEALTHCHECK defined by the image decides whether a task without a
+		// readiness probe is ready
+		healthy := m.Action != events.ActionHealthStatusUnhealthy
+		w.eventsMu.Lock()
+		w.unhealthy[id] = !healthy
+		w.eventsMu.Unlock()
+		if t.ReadinessProbe != nil {
+			return
+		}
+		w.dispatch(taskID, func() { w.setReady(taskID, id, healthy) })
+	}
+}
+
+// taskForContainer returns the task whose current container has the given ID, or nil.
+func (w *Worker) taskForContainer(containerID string) *task.Task {
+	for _, t := range w.GetTasks() {
+		if t.ContainerID == containerID {
+			return t
+		}
+	}
+	return nil
+}
+
+// isUnhealthy reports whether Docker last reported the container as unhealthy.
+func (w *Worker) isUnhealthy(containerID string) bool {
+	w.eventsMu.Lock()
+	defer w.eventsMu.Unlock()
+	return w.unhealthy[containerID]
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"log"
+	"sort"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// EvictionThresholds are the levels below (or, for pressure stall information, above) which
+// the worker starts evicting tasks. Zero values disable a threshold.
+type EvictionThresholds struct {
+	MemoryAvailable uint64  // bytes
+	DiskFree        uint64  // bytes, checked on the root filesystem and every monitored mount
+	MemoryPressure  float64 // percentage of the last 10 seconds some tasks were stalled on memory
+	IoPressure      float64 // percentage of the last 10 seconds some tasks were stalled on IO
+}
+
+// pressureConditions returns the resources s is running low on according to the thresholds.
+func (e EvictionThresholds) pressureConditions(s *stats.Stats) []node.PressureCondition {
+	var conditions []node.PressureCondition
+	memory := false
+	disk := false
+
+	// /proc/meminfo reports kibibytes
+	if e.MemoryAvailable > 0 && s.MemStats != nil && s.MemStats.MemTotal > 0 && s.MemAvailableKb()*1024 < e.MemoryAvailable {
+		memory = true
+	}
+	if e.DiskFree > 0 {
+		if s.DiskStats != nil && s.DiskStats.All > 0 && s.DiskFree() < e.DiskFree {
+			disk = true
+		}
+		for _, d := range s.MountStats {
+			if d.All > 0 && d.Free < e.DiskFree {
+				disk = true
+			}
+		}
+	}
+	if p := s.PressureStats; p != nil {
+		if e.MemoryPressure > 0 && p.Memory != nil && p.Memory.Some.Avg10 > e.MemoryPressure {
+			memory = true
+		}
+		if e.IoPressure > 0 && p.Io != nil && p.Io.Some.Avg10 > e.IoPressure {
+			disk = true
+		}
+	}
+
+	if memory {
+		conditions = append(conditions, node.MemoryPressure)
+	}
+	if disk {
+		conditions = append(conditions, node.DiskPressure)
+	}
+	return conditions
+}
+
+// checkPressure evaluates the eviction thresholds against the latest stats. While the worker is
+// under pressure it evicts one task per call, so the effect of each eviction is observed before
+// the next one.
+func (w *Worker) checkPressure() {
+	if w.Stats == nil {
+		return
+	}
+	w.Pressure = w.Eviction.pressureConditions(w.Stats)
+	if len(w.Pressure) == 0 {
+		return
+	}
+
+	victim := w.selectEvictionVictim()
+	if victim == nil {
+		log.Printf("[worker] under %v but there are no running tasks to evict\n", w.Pressure)
+		return
+	}
+	log.Printf("[worker] under %v, evicting task %s (priority %d)\n", w.Pressure, victim.ID, victim.Priority())
+	id, containerID := victim.ID.String(), victim.ContainerID
+	w.dispatch(id, func() { w.evictTask(id, containerID) })
+}
+
+// evictTask stops the task's container and records the task as Evicted, unless the task stopped
+// running that container since it was picked as the victim.
+func (w *Worker) evictTask(taskID string, containerID string) {
+	t := w.runningTask(taskID, containerID)
+	if t == nil {
+		log.Printf("[worker] task %s is no longer running, not evicting it\n", taskID)
+		return
+	}
+	result := w.stopTask(*t, task.Evicted)
+	if result.Error != nil {
+		log.Printf("[worker] error evicting task %s: %v\n", taskID, result.Error)
+	}
+}
+
+// selectEvictionVictim picks the running task with the lowest priority, and among those the one
+// using the most memory beyond what it requested. Tasks with operations in progress, such as an
+// eviction that is still stopping the container, are left out.
+func (w *Worker) selectEvictionVictim() *task.Task {
+	var running []*task.Task
+	for _, t := range w.GetTasks() {
+		if t.State == task.Running && !w.busy(t.ID.String()) {
+			running = append(running, t)
+		}
+	}
+	if len(running) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(running, func(i, j int) bool {
+		pi, pj := running[i].Priority(), running[j].Priority()
+		if pi != pj {
+			return pi < pj
+		}
+		return w.memoryOverRequest(running[i]) > w.memoryOverRequest(running[j])
+	})
+	return running[0]
+}
+
+// memoryOverRequest returns how many bytes of memory the task uses beyond its request, based on
+// the latest stats sample. It is negative for tasks using less than they requested.
+func (w *Worker) memoryOverRequest(t *task.Task) int64 {
+	w.historyMu.Lock()
+	h, ok := w.taskHistory[t.ID.String()]
+	w.historyMu.Unlock()
+	if !ok {
+		return 0
+	}
+	s := h.Latest()
+	if s == nil {
+		return 0
+	}
+	return int64(s.MemoryUsage) - int64(t.Memory)
+}
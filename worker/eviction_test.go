@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/c9s/goprocinfo/linux"
+	"github.com/google/uuid"
+)
+
+func TestPressureConditions(t *testing.T) {
+	e := EvictionThresholds{MemoryAvailable: 100 << 20, DiskFree: 1 << 30, MemoryPressure: 20, IoPressure: 50}
+	healthy := func() *stats.Stats {
+		return &stats.Stats{
+			MemStats:  &linux.MemInfo{MemTotal: 1 << 20, MemAvailable: 512 << 10}, // KiB
+			DiskStats: &linux.Disk{All: 10 << 30, Free: 5 << 30},
+			PressureStats: &stats.Pressure{
+				Memory: &stats.PressureStat{Some: stats.PressureLine{Avg10: 1}},
+				Io:     &stats.PressureStat{Some: stats.PressureLine{Avg10: 1}},
+			},
+		}
+	}
+
+	if got := e.pressureConditions(healthy()); len(got) != 0 {
+		t.Errorf("pressureConditions() of a healthy host = %v, want none", got)
+	}
+
+	s := healthy()
+	s.MemStats.MemAvailable = 50 << 10
+	if got := e.pressureConditions(s); !slices.Equal(got, []node.PressureCondition{node.MemoryPressure}) {
+		t.Errorf("pressureConditions() with 50 MiB available = %v, want MemoryPressure", got)
+	}
+
+	s = healthy()
+	s.MountStats = map[string]*linux.Disk{"/var/lib/docker": {All: 10 << 30, Free: 512 << 20}}
+	if got := e.pressureConditions(s); !slices.Equal(got, []node.PressureCondition{node.DiskPressure}) {
+		t.Errorf("pressureConditions() with a full mount = %v, want DiskPressure", got)
+	}
+
+	s = healthy()
+	s.PressureStats.Memory.Some.Avg10 = 30
+	s.PressureStats.Io.Some.Avg10 = 60
+	want := []node.PressureCondition{node.MemoryPressure, node.DiskPressure}
+	if got := e.pressureConditions(s); !slices.Equal(got, want) {
+		t.Errorf("pressureConditions() with memory and IO stalls = %v, want %v", got, want)
+	}
+
+	// disabled thresholds and missing stats never report pressure
+	if got := (EvictionThresholds{}).pressureConditions(s); len(got) != 0 {
+		t.Errorf("pressureConditions() without thresholds = %v, want none", got)
+	}
+	if got := e.pressureConditions(&stats.Stats{}); len(got) != 0 {
+		t.Errorf("pressureConditions() without stats = %v, want none", got)
+	}
+}
+
+func TestSelectEvictionVictim(t *testing.T) {
+	w := New("worker-1", "memory")
+	if v := w.selectEvictionVictim(); v != nil {
+		t.Fatalf("selectEvictionVictim() without tasks = %s, want none", v.Name)
+	}
+
+	add := func(name string, priorityClass string, state task.State, memory uint64, usage uint64) {
+		tk := task.Task{ID: uuid.New(), Name: name, PriorityClass: priorityClass, State: state, Memory: memory}
+		w.Db.Put(tk.ID.String(), &tk)
+		h := stats.NewTaskHistory(1)
+		h.Add(stats.TaskStats{TaskID: tk.ID, MemoryUsage: usage})
+		w.taskHistory[tk.ID.String()] = h
+	}
+	add("critical", "critical", task.Running, 100, 10_000)
+	add("stopped", "batch", task.Completed, 100, 10_000)
+	add("within-request", "low", task.Running, 1000, 900)
+	add("over-request", "low", task.Running, 100, 500)
+
+	v := w.selectEvictionVictim()
+	if v == nil || v.Name != "over-request" {
+		t.Errorf("selectEvictionVictim() = %v, want the lowest priority task using most memory over its request", v)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 func (a *Api) StartTaskHandler(w http.ResponseWriter, r *http.Request) {
@@ -64,8 +65,130 @@ func (a *Api) StopTaskHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(204)
 }
 
+// GetStatsHandler returns the latest stats sample, or a time series of the recorded samples
+// when the since or step query parameters are given.
 func (a *Api) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isSeriesRequest(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		json.NewEncoder(w).Encode(a.Worker.Stats)
+		return
+	}
+
+	since, step, err := parseSeriesParams(r)
+	if err != nil {
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Worker.StatsHistory.Series(since, step))
+}
+
+func isSeriesRequest(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has("since") || q.Has("step")
+}
+
+// parseSeriesParams reads the since parameter, either an RFC 3339 timestamp or a duration
+// relative to now such as 10m, and the step parameter, a duration.
+func parseSeriesParams(r *http.Request) (time.Time, time.Duration, error) {
+	var since time.Time
+	var step time.Duration
+	q := r.URL.Query()
+
+	if v := q.Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			since = time.Now().UTC().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			since = t
+		} else {
+			return since, step, fmt.Errorf("invalid since %q, expected a duration or an RFC 3339 timestamp", v)
+		}
+	}
+
+	if v := q.Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return since, step, fmt.Errorf("invalid step %q, expected a duration", v)
+		}
+		step = d
+	}
+	return since, step, nil
+}
+
+// GetTasksStatsHandler returns the latest stats sample of every running task.
+func (a *Api) GetTasksStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(a.Worker.GetAllTaskStats())
+}
+
+func (a *Api) GetTaskStatsHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "taskID")
+	tID, err := uuid.Parse(taskID)
+	if err != nil {
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        fmt.Sprintf("invalid task ID %s", taskID),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	if isSeriesRequest(r) {
+		a.getTaskStatsSeries(w, r, tID.String())
+		return
+	}
+
+	s, err := a.Worker.GetTaskStats(tID.String())
+	if err != nil {
+		log.Printf("Error getting stats for task %v: %v\n", tID, err)
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	json.NewEncoder(w).Encode(s)
+}
+
+func (a *Api) getTaskStatsSeries(w http.ResponseWriter, r *http.Request, taskID string) {
+	since, step, err := parseSeriesParams(r)
+	if err != nil {
+		w.WriteHeader(400)
+		e := ErrResponse{
+			HTTPStatusCode: 400,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	series, err := a.Worker.GetTaskStatsSeries(taskID, since, step)
+	if err != nil {
+		w.WriteHeader(404)
+		e := ErrResponse{
+			HTTPStatusCode: 404,
+			Message:        err.Error(),
+		}
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
-	json.NewEncoder(w).Encode(a.Worker.Stats)
+	json.NewEncoder(w).Encode(series)
 }
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"github.com/ahmadateya/my-own-k8s/metrics"
+	"github.com/ahmadateya/my-own-k8s/stats"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+var (
+	registry    = metrics.NewRegistry()
+	httpMetrics = metrics.NewHTTPMetrics(registry, "cube_worker")
+
+	hostStats       = metrics.NewStatsGauges(registry, "cube_worker", "worker")
+	queueDepth      = registry.NewGaugeVec("cube_worker_queue_depth", "Number of tasks waiting in the worker's queue.", "worker")
+	tasksByState    = registry.NewGaugeVec("cube_worker_tasks", "Number of tasks known to the worker by state.", "worker", "state")
+	taskCpuUsage    = registry.NewGaugeVec("cube_worker_task_cpu_usage_ratio", "CPU used by the task's container, 1 per fully used core.", "worker", "task_id", "task_name", "job")
+	taskMemoryUsage = registry.NewGaugeVec("cube_worker_task_memory_usage_bytes", "Memory used by the task's container.", "worker", "task_id", "task_name", "job")
+	taskRestarts    = registry.NewCounterVec("cube_worker_task_restarts_total", "Number of task containers restarted by the worker.", "worker")
+)
+
+// updateMetrics refreshes the gauges that mirror the worker's state right before a scrape.
+func (w *Worker) updateMetrics() {
+	hostStats.Set(w.Stats, w.Name)
+	queueDepth.Set(float64(w.QueueLen()), w.Name)
+
+	counts := make(map[task.State]int)
+	for _, t := range w.GetTasks() {
+		counts[t.State]++
+	}
+	for _, s := range []task.State{task.Pending, task.Scheduled, task.Running, task.Completed, task.Failed, task.Evicted, task.CrashLoopBackOff} {
+		tasksByState.Set(float64(counts[s]), w.Name, s.String())
+	}
+}
+
+// setTaskMetrics replaces the task usage gauges with the latest samples, labeled with the labels
+// of the tasks they were taken from, by task ID.
+func (w *Worker) setTaskMetrics(samples []*stats.TaskStats, labels map[string]map[string]string) {
+	taskCpuUsage.Reset()
+	taskMemoryUsage.Reset()
+	for _, s := range samples {
+		l, ok := labels[s.TaskID.String()]
+		if !ok {
+			continue
+		}
+		values := []string{l[task.LabelWorker], l[task.LabelTaskID], l[task.LabelTaskName], l[task.LabelJob]}
+		taskCpuUsage.Set(s.CpuPercent/100, values...)
+		taskMemoryUsage.Set(float64(s.MemoryUsage), values...)
+	}
+}
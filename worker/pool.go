@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"log"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// DefaultParallelism is the number of task operations, such as starting or stopping a task's
+// container, a worker runs at the same time.
+const DefaultParallelism = 4
+
+// enqueue adds a task to the worker's queue. The queue is shared by the API handlers and RunTasks.
+func (w *Worker) enqueue(t task.Task) {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	w.Queue.Enqueue(t)
+}
+
+func (w *Worker) dequeue() (task.Task, bool) {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	if w.Queue.Len() == 0 {
+		return task.Task{}, false
+	}
+	return w.Queue.Dequeue().(task.Task), true
+}
+
+// QueueLen returns the number of tasks waiting in the worker's queue.
+func (w *Worker) QueueLen() int {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	return w.Queue.Len()
+}
+
+// dispatch runs op in the background once one of the worker's Parallelism slots is free. The
+// operations of a task run one at a time in the order they were dispatched, so that e.g. a stop
+// never overtakes the start before it, while operations of different tasks run concurrently.
+// The slots are created on the first call, so Parallelism must be set before any task operation
+// is dispatched.
+func (w *Worker) dispatch(taskID string, op func()) {
+	w.lanesMu.Lock()
+	if w.slots == nil {
+		w.slots = make(chan struct{}, max(w.Parallelism, 1))
+	}
+	pending, busy := w.lanes[taskID]
+	w.lanes[taskID] = append(pending, op)
+	w.lanesMu.Unlock()
+	if !busy {
+		go w.runLane(taskID)
+	}
+}
+
+// runLane runs the operations dispatched for a task until there are none left.
+func (w *Worker) runLane(taskID string) {
+	for {
+		w.lanesMu.Lock()
+		ops := w.lanes[taskID]
+		if len(ops) == 0 {
+			delete(w.lanes, taskID)
+			w.lanesMu.Unlock()
+			// a restart may have been held back while the task was busy
+			w.notify()
+			return
+		}
+		op := ops[0]
+		w.lanes[taskID] = ops[1:]
+		slots := w.slots
+		w.lanesMu.Unlock()
+
+		slots <- struct{}{}
+		op()
+		<-slots
+	}
+}
+
+// busy reports whether operations of the task are running or waiting to run.
+func (w *Worker) busy(taskID string) bool {
+	w.lanesMu.Lock()
+	defer w.lanesMu.Unlock()
+	_, ok := w.lanes[taskID]
+	return ok
+}
+
+// runningTask re-reads the task from the store, to be called from the task's lane. It returns
+// the task if it is still running the given container, or nil if the task moved on since the
+// caller looked at it, e.g. because it was stopped in the meantime.
+func (w *Worker) runningTask(taskID string, containerID string) *task.Task {
+	result, err := w.Db.Get(taskID)
+	if err != nil {
+		return nil
+	}
+	t, ok := result.(*task.Task)
+	if !ok || t.State != task.Running || t.ContainerID != containerID {
+		return nil
+	}
+	return t
+}
+
+// runQueued dispatches the tasks waiting in the queue.
+func (w *Worker) runQueued() {
+	for {
+		t, ok := w.dequeue()
+		if !ok {
+			return
+		}
+		w.dispatch(t.ID.String(), func() {
+			result := w.runTask(t)
+			if result.Error != nil {
+				log.Printf("Error running task: %v\n", result.Error)
+			}
+		})
+	}
+}
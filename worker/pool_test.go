@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitIdle waits until no operations of the tasks are running or waiting to run.
+func waitIdle(t *testing.T, w *Worker, taskIDs ...string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range taskIDs {
+		for w.busy(id) {
+			if time.Now().After(deadline) {
+				t.Fatalf("operations of task %s didn't finish", id)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestDispatchRunsOperationsOfATaskInOrder(t *testing.T) {
+	w := New("worker-1", "memory")
+	w.Parallelism = 4
+
+	var mu sync.Mutex
+	order := make(map[string][]int)
+	running := make(map[string]bool)
+	for op := 0; op < 20; op++ {
+		for _, id := range []string{"a", "b", "c"} {
+			id, op := id, op
+			w.dispatch(id, func() {
+				mu.Lock()
+				if running[id] {
+					t.Errorf("operations of task %s overlap", id)
+				}
+				running[id] = true
+				mu.Unlock()
+
+				time.Sleep(100 * time.Microsecond)
+
+				mu.Lock()
+				running[id] = false
+				order[id] = append(order[id], op)
+				mu.Unlock()
+			})
+		}
+	}
+	waitIdle(t, w, "a", "b", "c")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, ops := range order {
+		for i, op := range ops {
+			if op != i {
+				t.Fatalf("task %s ran its operations in order %v", id, ops)
+			}
+		}
+		if len(ops) != 20 {
+			t.Errorf("task %s ran %d operations, want 20", id, len(ops))
+		}
+	}
+}
+
+func TestDispatchRunsTasksConcurrently(t *testing.T) {
+	w := New("worker-1", "memory")
+	w.Parallelism = 2
+
+	// the operations only both start if they run at the same time
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	for _, id := range []string{"a", "b"} {
+		w.dispatch(id, func() {
+			started <- id
+			<-release
+		})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("operations of different tasks didn't run concurrently")
+		}
+	}
+	if !w.busy("a") || !w.busy("b") {
+		t.Error("tasks with running operations should be busy")
+	}
+	close(release)
+	waitIdle(t, w, "a", "b")
+}
+
+func TestDispatchLimitsParallelism(t *testing.T) {
+	w := New("worker-1", "memory")
+	w.Parallelism = 1
+
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	for _, id := range []string{"a", "b"} {
+		w.dispatch(id, func() {
+			started <- id
+			<-release
+		})
+	}
+
+	<-started
+	select {
+	case id := <-started:
+		t.Fatalf("operation of task %s started while the only slot was taken", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-started
+	waitIdle(t, w, "a", "b")
+}
+
+func TestDispatchWithoutRunTasks(t *testing.T) {
+	// probes, container events and evictions dispatch operations before or without RunTasks
+	w := New("worker-1", "memory")
+
+	done := make(chan struct{})
+	w.dispatch("a", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("operation dispatched before RunTasks didn't run")
+	}
+	waitIdle(t, w, "a")
+}
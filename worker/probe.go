@@ -0,0 +1,244 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/docker/go-connections/nat"
+)
+
+type probeKind string
+
+const (
+	startupProbe   probeKind = "startup"
+	livenessProbe  probeKind = "liveness"
+	readinessProbe probeKind = "readiness"
+)
+
+// RunProbes checks the probes of the running tasks as they come due. Probes are run one at a
+// time, so a slow probe delays the others by at most its timeout. Their results are recorded on
+// the task's lane, and tasks with operations in progress are skipped.
+func (w *Worker) RunProbes() {
+	for {
+		w.runProbes()
+		time.Sleep(time.Second)
+	}
+}
+
+func (w *Worker) runProbes() {
+	now := time.Now()
+	due := make(map[string]bool)
+	for _, t := range w.GetTasks() {
+		if t.State != task.Running || w.busy(t.ID.String()) {
+			continue
+		}
+
+		// the other probes wait until the startup probe has passed
+		if t.StartupProbe != nil && (t.Probes.Startup == nil || t.Probes.Startup.Result != task.ProbeSuccess) {
+			w.probeIfDue(t, startupProbe, t.StartupProbe, now, due)
+			continue
+		}
+		if p := t.Liveness(); p != nil {
+			w.probeIfDue(t, livenessProbe, p, now, due)
+		}
+		if t.ReadinessProbe != nil {
+			w.probeIfDue(t, readinessProbe, t.ReadinessProbe, now, due)
+		} else if !t.Ready && !w.isUnhealthy(t.ContainerID) {
+			id, containerID := t.ID.String(), t.ContainerID
+			w.dispatch(id, func() { w.setReady(id, containerID, true) })
+		}
+	}
+
+	for key := range w.nextProbe {
+		if !due[key] {
+			delete(w.nextProbe, key)
+		}
+	}
+}
+
+func (w *Worker) probeIfDue(t *task.Task, kind probeKind, p *task.Probe, now time.Time, due map[string]bool) {
+	key := fmt.Sprintf("%s/%s/%s", t.ID, t.ContainerID, kind)
+	due[key] = true
+	next, ok := w.nextProbe[key]
+	if !ok {
+		next = t.StartTime.Add(p.InitialDelay())
+	}
+	if now.Before(next) {
+		w.nextProbe[key] = next
+		return
+	}
+	w.nextProbe[key] = now.Add(p.Period())
+
+	err := w.probe(t, p)
+	id, containerID := t.ID.String(), t.ContainerID
+	w.dispatch(id, func() { w.recordProbe(id, containerID, kind, p, err) })
+}
+
+// probe runs a single check, returning why it failed or nil if it passed.
+func (w *Worker) probe(t *task.Task, p *task.Probe) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout())
+	defer cancel()
+
+	switch {
+	case p.HTTPGet != nil:
+		port, err := hostPort(t.HostPorts, p.HTTPGet.Port)
+		if err != nil {
+			return err
+		}
+		url := fmt.Sprintf("http://%s%s", net.JoinHostPort("127.0.0.1", port), p.HTTPGet.Path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+		}
+		return nil
+
+	case p.TCPSocket != nil:
+		port, err := hostPort(t.HostPorts, p.TCPSocket.Port)
+		if err != nil {
+			return err
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+
+	case p.Exec != nil:
+		config := task.NewConfig(t)
+		d, err := task.NewDocker(config)
+		if err != nil {
+			return err
+		}
+		resp := d.Exec(ctx, t.ContainerID, p.Exec.Command)
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if resp.ExitCode != 0 {
+			return fmt.Errorf("command %v exited with status %d: %s", p.Exec.Command, resp.ExitCode, resp.Output)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("probe has no HTTPGet, TCPSocket or Exec action")
+	}
+}
+
+// hostPort returns the host port a container port is published on. Port 0 picks the first
+// published port.
+func hostPort(ports nat.PortMap, port int) (string, error) {
+	for p, bindings := range ports {
+		if len(bindings) == 0 {
+			continue
+		}
+		if port == 0 || p.Int() == port {
+			return bindings[0].HostPort, nil
+		}
+	}
+	return "", fmt.Errorf("container port %d is not published", port)
+}
+
+// recordProbe updates the probe's status on the task and acts on a probe that changed result:
+// a failed startup or liveness probe stops the container and hands the task to its restart
+// policy, while the readiness probe only toggles the task's Ready flag. The result is dropped
+// if the task no longer runs the probed container.
+func (w *Worker) recordProbe(taskID string, containerID string, kind probeKind, p *task.Probe, err error) {
+	t := w.runningTask(taskID, containerID)
+	if t == nil {
+		return
+	}
+
+	status := probeStatus(t, kind)
+	status.LastProbeTime = time.Now().UTC()
+	if err == nil {
+		status.ConsecutiveSuccesses++
+		status.ConsecutiveFailures = 0
+		status.Message = ""
+		if status.ConsecutiveSuccesses >= p.Successes() {
+			status.Result = task.ProbeSuccess
+		}
+	} else {
+		status.ConsecutiveFailures++
+		status.ConsecutiveSuccesses = 0
+		status.Message = err.Error()
+		if status.ConsecutiveFailures >= p.Failures() {
+			status.Result = task.ProbeFailure
+		}
+	}
+
+	if kind == readinessProbe {
+		ready := status.Result == task.ProbeSuccess
+		if ready != t.Ready {
+			log.Printf("[worker] task %s readiness changed to %v\n", t.ID, ready)
+		}
+		t.Ready = ready
+	}
+	w.saveTask(t)
+
+	if status.Result == task.ProbeFailure && kind != readinessProbe {
+		log.Printf("[worker] %s probe of task %s failed %d times, stopping it: %s\n", kind, t.ID, status.ConsecutiveFailures, status.Message)
+		reason := ExitLivenessProbeFailed
+		if kind == startupProbe {
+			reason = ExitStartupProbeFailed
+		}
+		// apply the restart policy before stopping the container, so that the container's die
+		// event isn't taken for an exit; the container is stopped with SIGTERM
+		w.containerExited(t, 143, reason)
+
+		config := task.NewConfig(t)
+		d, err := task.NewDocker(config)
+		if err != nil {
+			log.Printf("[worker] error stopping task %s: %v\n", t.ID, err)
+			return
+		}
+		result := d.Stop(t.ContainerID)
+		if result.Error != nil {
+			log.Printf("[worker] error stopping task %s: %v\n", t.ID, result.Error)
+		}
+	}
+}
+
+// setReady records whether the task's container is ready, unless the task no longer runs it.
+func (w *Worker) setReady(taskID string, containerID string, ready bool) {
+	t := w.runningTask(taskID, containerID)
+	if t == nil || t.Ready == ready {
+		return
+	}
+	log.Printf("[worker] task %s readiness changed to %v\n", t.ID, ready)
+	t.Ready = ready
+	w.saveTask(t)
+}
+
+func probeStatus(t *task.Task, kind probeKind) *task.ProbeStatus {
+	var status **task.ProbeStatus
+	switch kind {
+	case startupProbe:
+		status = &t.Probes.Startup
+	case livenessProbe:
+		status = &t.Probes.Liveness
+	default:
+		status = &t.Probes.Readiness
+	}
+	// work on a copy, the status may be shared with copies of the task being read elsewhere
+	s := &task.ProbeStatus{Result: task.ProbeUnknown}
+	if *status != nil {
+		c := **status
+		s = &c
+	}
+	*status = s
+	return s
+}
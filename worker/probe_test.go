@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+)
+
+// publishedOn returns the port map of a container whose port 80 is published on the port of addr.
+func publishedOn(t *testing.T, addr string) nat.PortMap {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: port}}}
+}
+
+func TestHostPort(t *testing.T) {
+	ports := nat.PortMap{
+		"80/tcp":  {{HostPort: "8080"}},
+		"443/tcp": {},
+	}
+	if p, err := hostPort(ports, 80); err != nil || p != "8080" {
+		t.Errorf("hostPort(80) = %q, %v, want 8080", p, err)
+	}
+	if p, err := hostPort(ports, 0); err != nil || p != "8080" {
+		t.Errorf("hostPort(0) = %q, %v, want the first published port", p, err)
+	}
+	if _, err := hostPort(ports, 443); err == nil {
+		t.Error("hostPort() of an unpublished port should return an error")
+	}
+}
+
+func TestHTTPGetProbe(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+	w := New("worker-1", "memory")
+	tk := &task.Task{HostPorts: publishedOn(t, s.Listener.Addr().String())}
+
+	if err := w.probe(tk, &task.Probe{HTTPGet: &task.HTTPGetAction{Path: "/healthz", Port: 80}}); err != nil {
+		t.Errorf("probe() of a healthy endpoint error = %v", err)
+	}
+	if err := w.probe(tk, &task.Probe{HTTPGet: &task.HTTPGetAction{Path: "/ready"}}); err == nil {
+		t.Error("probe() of an endpoint returning 503 should fail")
+	}
+}
+
+func TestTCPSocketProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := New("worker-1", "memory")
+	tk := &task.Task{HostPorts: publishedOn(t, l.Addr().String())}
+	p := &task.Probe{TCPSocket: &task.TCPSocketAction{Port: 80}}
+
+	if err := w.probe(tk, p); err != nil {
+		t.Errorf("probe() of a listening port error = %v", err)
+	}
+	l.Close()
+	if err := w.probe(tk, p); err == nil {
+		t.Error("probe() of a closed port should fail")
+	}
+}
+
+func TestRecordReadinessProbe(t *testing.T) {
+	w := New("worker-1", "memory")
+	tk := &task.Task{ID: uuid.New(), State: task.Running, ContainerID: "c1"}
+	w.saveTask(tk)
+	p := &task.Probe{SuccessThreshold: 2, FailureThreshold: 2}
+	id := tk.ID.String()
+
+	ready := func() bool {
+		return w.runningTask(id, "c1").Ready
+	}
+
+	w.recordProbe(id, "c1", readinessProbe, p, nil)
+	if ready() {
+		t.Error("task should not be ready before the success threshold is met")
+	}
+	w.recordProbe(id, "c1", readinessProbe, p, nil)
+	if !ready() {
+		t.Error("task should be ready once the success threshold is met")
+	}
+
+	w.recordProbe(id, "c1", readinessProbe, p, errors.New("refused"))
+	if !ready() {
+		t.Error("task should stay ready before the failure threshold is met")
+	}
+	w.recordProbe(id, "c1", readinessProbe, p, errors.New("refused"))
+	if ready() {
+		t.Error("task should not be ready once the failure threshold is met")
+	}
+	if s := w.runningTask(id, "c1").Probes.Readiness; s.Result != task.ProbeFailure || s.Message != "refused" {
+		t.Errorf("readiness is %s (%s), want %s (refused)", s.Result, s.Message, task.ProbeFailure)
+	}
+
+	// results for a container the task no longer runs are dropped
+	w.recordProbe(id, "c0", readinessProbe, p, nil)
+	w.recordProbe(id, "c0", readinessProbe, p, nil)
+	if ready() {
+		t.Error("a probe of a previous container should not change readiness")
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// pushRetryInterval is how long PushTaskUpdates waits before pushing again after a failure.
+const pushRetryInterval = 5 * time.Second
+
+// saveTask stores the task with a new resource version and queues it to be pushed to the manager.
+func (w *Worker) saveTask(t *task.Task) error {
+	w.updatesMu.Lock()
+	defer w.updatesMu.Unlock()
+
+	w.resourceVersion++
+	t.ResourceVersion = w.resourceVersion
+	err := w.Db.Put(t.ID.String(), t)
+	if err != nil {
+		return err
+	}
+	w.updates[t.ID.String()] = *t
+	w.wakePush()
+	return nil
+}
+
+func (w *Worker) wakePush() {
+	select {
+	case w.pushWake <- struct{}{}:
+	default:
+	}
+}
+
+// PushTaskUpdates reports the tasks to the manager as soon as they change. Updates that can't be
+// delivered are retried; the manager also polls the worker's tasks periodically to resync.
+func (w *Worker) PushTaskUpdates(manager string, address string) {
+	url := fmt.Sprintf("http://%s/tasks/status", manager)
+	for {
+		<-w.pushWake
+		updates := w.takeUpdates()
+		if len(updates) == 0 {
+			continue
+		}
+
+		report := node.TaskStatusReport{Address: address, Tasks: updates}
+		err := w.postToManager(url, report, http.StatusNoContent)
+		if err != nil {
+			log.Printf("[worker] unable to push %d task updates to manager %s: %v\n", len(updates), manager, err)
+			w.requeueUpdates(updates)
+			time.Sleep(pushRetryInterval)
+		}
+	}
+}
+
+func (w *Worker) takeUpdates() []task.Task {
+	w.updatesMu.Lock()
+	defer w.updatesMu.Unlock()
+	updates := make([]task.Task, 0, len(w.updates))
+	for id, t := range w.updates {
+		updates = append(updates, t)
+		delete(w.updates, id)
+	}
+	return updates
+}
+
+// requeueUpdates puts back the updates that failed to be pushed, unless the task changed again since.
+func (w *Worker) requeueUpdates(updates []task.Task) {
+	w.updatesMu.Lock()
+	defer w.updatesMu.Unlock()
+	for _, t := range updates {
+		if _, ok := w.updates[t.ID.String()]; !ok {
+			w.updates[t.ID.String()] = t
+		}
+	}
+	w.wakePush()
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"log"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/docker/docker/api/types"
+)
+
+// Reconcile brings the worker's tasks in line with the containers Docker has for it, which may
+// have drifted while the worker was down. It must run before the worker starts processing tasks:
+//   - containers of known tasks are adopted, and those that exited meanwhile go through the task's
+//     restart policy
+//   - containers of unknown tasks are removed with the task's networks and volumes, or left alone
+//     if RemoveUnknownContainers is false
+//   - running tasks whose container is gone are marked Failed
+//   - scheduled tasks are queued again, since the queue doesn't survive a restart
+//
+// Containers are recognized by the labels StartTask sets, so the worker must keep its name
+// across restarts.
+func (w *Worker) Reconcile() error {
+	d, err := task.NewDocker(&task.Config{})
+	if err != nil {
+		return err
+	}
+	containers, err := d.List(w.ownedBy(""))
+	if err != nil {
+		return err
+	}
+	w.reconcile(d, containers)
+	return nil
+}
+
+// containerRuntime is the part of the Docker client Reconcile needs besides listing containers.
+type containerRuntime interface {
+	Stop(id string) task.DockerResult
+	Inspect(containerID string) task.DockerInspectResponse
+	RemoveLabeled(labels map[string]string) error
+}
+
+func (w *Worker) reconcile(d containerRuntime, containers []types.Container) {
+	tasks := make(map[string]*task.Task)
+	for _, t := range w.GetTasks() {
+		tasks[t.ID.String()] = t
+	}
+
+	found := make(map[string]bool)
+	for _, c := range containers {
+		id := c.Labels[task.LabelTaskID]
+		t, ok := tasks[id]
+		if !ok {
+			w.removeUnknownTask(d, id, c)
+			continue
+		}
+		if t.ContainerID != c.ID {
+			// left over from a start or restart the worker didn't get to record
+			log.Printf("[worker] removing stale container %s of task %s\n", c.ID, id)
+			d.Stop(c.ID)
+			continue
+		}
+		found[t.ID.String()] = true
+		w.adoptContainer(d, t)
+	}
+
+	for id, t := range tasks {
+		if t.State == task.Running && !found[id] {
+			log.Printf("[worker] container %s of task %s is gone, marking the task %s\n", t.ContainerID, id, task.Failed)
+			t.State = task.Failed
+			t.ExitCode = -1
+			t.ExitReason = ExitContainerMissing
+			t.Ready = false
+			w.saveTask(t)
+		}
+		if t.State == task.Scheduled {
+			w.AddTask(*t)
+		}
+	}
+}
+
+// adoptContainer takes over a known task's container, applying the task's restart policy if the
+// container exited while the worker was down.
+func (w *Worker) adoptContainer(d containerRuntime, t *task.Task) {
+	resp := d.Inspect(t.ContainerID)
+	if resp.Error != nil || resp.Container == nil || resp.Container.State == nil {
+		log.Printf("[worker] unable to inspect container %s of task %s: %v\n", t.ContainerID, t.ID, resp.Error)
+		return
+	}
+
+	state := resp.Container.State
+	if state.Running {
+		switch t.State {
+		case task.Running:
+			log.Printf("[worker] adopting running container %s of task %s\n", t.ContainerID, t.ID)
+			if resp.Container.NetworkSettings != nil {
+				t.HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
+			}
+			w.saveTask(t)
+		case task.Completed, task.Failed, task.Evicted:
+			// the worker went down while stopping the task
+			log.Printf("[worker] stopping container %s of %s task %s\n", t.ContainerID, t.State, t.ID)
+			w.stopTask(*t, t.State)
+		}
+		return
+	}
+
+	if t.State == task.Running {
+		reason := ExitCompleted
+		switch {
+		case state.OOMKilled:
+			reason = ExitOOMKilled
+		case state.ExitCode != 0:
+			reason = ExitError
+		}
+		log.Printf("[worker] container %s of task %s exited while the worker was down\n", t.ContainerID, t.ID)
+		w.containerExited(t, state.ExitCode, reason)
+	}
+}
+
+// removeUnknownTask removes the container of a task the worker doesn't know about, along with the
+// networks and volumes created for the task.
+func (w *Worker) removeUnknownTask(d containerRuntime, taskID string, c types.Container) {
+	name := c.Labels[task.LabelTaskName]
+	if !w.RemoveUnknownContainers {
+		log.Printf("[worker] leaving container %s of unknown task %s (%s) alone\n", c.ID, taskID, name)
+		return
+	}
+	log.Printf("[worker] removing container %s of unknown task %s (%s)\n", c.ID, taskID, name)
+	if taskID == "" {
+		d.Stop(c.ID)
+		return
+	}
+	err := d.RemoveLabeled(w.ownedBy(taskID))
+	if err != nil {
+		log.Printf("[worker] error removing Docker objects of task %s: %v\n", taskID, err)
+	}
+}
+
+// ownedBy returns the labels selecting the Docker objects the worker created for a task, or for
+// any task if taskID is empty.
+func (w *Worker) ownedBy(taskID string) map[string]string {
+	labels := map[string]string{task.LabelWorker: w.Name}
+	if taskID != "" {
+		labels[task.LabelTaskID] = taskID
+	}
+	return labels
+}
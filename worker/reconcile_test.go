@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+)
+
+// fakeRuntime answers Reconcile's Docker calls from a set of container states.
+type fakeRuntime struct {
+	states  map[string]*types.ContainerState // [containerID]state
+	stopped []string                         // container IDs
+	removed []string                         // task IDs
+}
+
+func (f *fakeRuntime) Stop(id string) task.DockerResult {
+	f.stopped = append(f.stopped, id)
+	return task.DockerResult{Action: "stop", ContainerId: id}
+}
+
+func (f *fakeRuntime) Inspect(containerID string) task.DockerInspectResponse {
+	state, ok := f.states[containerID]
+	if !ok {
+		return task.DockerInspectResponse{}
+	}
+	return task.DockerInspectResponse{Container: &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{ID: containerID, State: state}}}
+}
+
+func (f *fakeRuntime) RemoveLabeled(labels map[string]string) error {
+	f.removed = append(f.removed, labels[task.LabelTaskID])
+	return nil
+}
+
+// labeledContainer returns a container labeled as created by worker w for a task.
+func labeledContainer(w *Worker, id string, taskID string) types.Container {
+	labels := map[string]string{task.LabelWorker: w.Name}
+	if taskID != "" {
+		labels[task.LabelTaskID] = taskID
+	}
+	return types.Container{ID: id, Labels: labels}
+}
+
+func storedTask(t *testing.T, w *Worker, id uuid.UUID) *task.Task {
+	result, err := w.Db.Get(id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(*task.Task)
+}
+
+func TestReconcile(t *testing.T) {
+	w := New("worker-1", "memory")
+	running := &task.Task{ID: uuid.New(), State: task.Running, ContainerID: "running"}
+	exited := &task.Task{ID: uuid.New(), State: task.Running, ContainerID: "exited"}
+	stale := &task.Task{ID: uuid.New(), State: task.Running, ContainerID: "restarted"}
+	missing := &task.Task{ID: uuid.New(), State: task.Running, ContainerID: "missing"}
+	scheduled := &task.Task{ID: uuid.New(), State: task.Scheduled}
+	for _, tk := range []*task.Task{running, exited, stale, missing, scheduled} {
+		w.saveTask(tk)
+	}
+	unknown := uuid.New().String()
+
+	d := &fakeRuntime{states: map[string]*types.ContainerState{
+		"running":   {Running: true},
+		"exited":    {ExitCode: 1},
+		"restarted": {Running: true},
+	}}
+	w.reconcile(d, []types.Container{
+		labeledContainer(w, "running", running.ID.String()),
+		labeledContainer(w, "exited", exited.ID.String()),
+		labeledContainer(w, "restarted", stale.ID.String()),
+		labeledContainer(w, "previous", stale.ID.String()),
+		labeledContainer(w, "orphan", unknown),
+		labeledContainer(w, "unlabeled", ""),
+	})
+
+	if got := storedTask(t, w, running.ID); got.State != task.Running {
+		t.Errorf("adopted task is %s, want Running", got.State)
+	}
+	if got := storedTask(t, w, exited.ID); got.State != task.CrashLoopBackOff || got.ExitReason != ExitError {
+		t.Errorf("task whose container exited is %s (%s), want CrashLoopBackOff (%s)", got.State, got.ExitReason, ExitError)
+	}
+	if got := storedTask(t, w, stale.ID); got.State != task.Running {
+		t.Errorf("task with a stale container is %s, want Running", got.State)
+	}
+	if got := storedTask(t, w, missing.ID); got.State != task.Failed || got.ExitReason != ExitContainerMissing {
+		t.Errorf("task whose container is gone is %s (%s), want Failed (%s)", got.State, got.ExitReason, ExitContainerMissing)
+	}
+	if w.Queue.Len() != 1 {
+		t.Errorf("queue has %d tasks, want the scheduled task requeued", w.Queue.Len())
+	}
+
+	wantStopped := []string{"previous", "unlabeled"}
+	if len(d.stopped) != len(wantStopped) || d.stopped[0] != wantStopped[0] || d.stopped[1] != wantStopped[1] {
+		t.Errorf("stopped containers %v, want %v", d.stopped, wantStopped)
+	}
+	if len(d.removed) != 1 || d.removed[0] != unknown {
+		t.Errorf("removed the objects of tasks %v, want only %s", d.removed, unknown)
+	}
+}
+
+func TestReconcileLeavesUnknownContainersAlone(t *testing.T) {
+	w := New("worker-1", "memory")
+	w.RemoveUnknownContainers = false
+
+	d := &fakeRuntime{}
+	w.reconcile(d, []types.Container{
+		labeledContainer(w, "orphan", uuid.New().String()),
+		labeledContainer(w, "unlabeled", ""),
+	})
+
+	if len(d.stopped) != 0 || len(d.removed) != 0 {
+		t.Errorf("stopped %v and removed the objects of %v, want nothing touched", d.stopped, d.removed)
+	}
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/node"
+	"github.com/ahmadateya/my-own-k8s/stats"
+)
+
+// RegisterWithManager announces the worker to the manager and then sends a heartbeat every
+// interval. If the manager stops recognizing the worker, for example after a manager restart,
+// the worker registers again.
+func (w *Worker) RegisterWithManager(manager string, address string, labels map[string]string, interval time.Duration) {
+	registered := false
+	for {
+		if !registered {
+			err := w.register(manager, address, labels)
+			if err != nil {
+				log.Printf("[worker] unable to register with manager %s: %v\n", manager, err)
+			} else {
+				log.Printf("[worker] registered with manager %s as %s\n", manager, address)
+				registered = true
+			}
+		} else {
+			err := w.heartbeat(manager, address)
+			if err != nil {
+				log.Printf("[worker] heartbeat to manager %s failed: %v\n", manager, err)
+				registered = false
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (w *Worker) register(manager string, address string, labels map[string]string) error {
+	s := stats.GetStats(w.MountPaths...)
+	cores := s.Cores()
+	if cores == 0 {
+		cores = runtime.NumCPU()
+	}
+	reg := node.Registration{
+		Name:    w.Name,
+		Address: address,
+		Memory:  s.MemTotalKb(),
+		Disk:    s.DiskTotal(),
+		Cores:   cores,
+		Labels:  labels,
+	}
+	return w.postToManager(fmt.Sprintf("http://%s/nodes/register", manager), reg, http.StatusCreated)
+}
+
+func (w *Worker) heartbeat(manager string, address string) error {
+	hb := node.Heartbeat{
+		Address:   address,
+		TaskCount: w.TaskCount,
+		Pressure:  w.Pressure,
+		Timestamp: time.Now().UTC(),
+	}
+	return w.postToManager(fmt.Sprintf("http://%s/nodes/heartbeat", manager), hb, http.StatusNoContent)
+}
+
+func (w *Worker) postToManager(url string, body interface{}, expectedStatus int) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		e := ErrResponse{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, e.Message)
+	}
+	return nil
+}
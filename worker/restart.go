@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"github.com/ahmadateya/my-own-k8s/task"
+)
+
+// Reasons recorded in a task's ExitReason.
+const (
+	ExitCompleted           = "Completed"
+	ExitError               = "Error"
+	ExitOOMKilled           = "OOMKilled"
+	ExitContainerMissing    = "ContainerMissing"
+	ExitStartupProbeFailed  = "StartupProbeFailed"
+	ExitLivenessProbeFailed = "LivenessProbeFailed"
+)
+
+// containerExited records that the task's container is no longer running and applies the task's
+// restart policy: the task either waits in CrashLoopBackOff for restartTasks to start it again,
+// or completes or fails for good.
+func (w *Worker) containerExited(t *task.Task, exitCode int, reason string) {
+	now := time.Now().UTC()
+	t.ExitCode = exitCode
+	t.ExitReason = reason
+	t.FinishTime = now
+	t.Ready = false
+
+	failed := reason != ExitCompleted
+	if t.ShouldRestart(failed) {
+		delay := t.RestartBackoff(w.RestartBackoff, w.MaxRestartBackoff)
+		t.State = task.CrashLoopBackOff
+		t.RestartAt = now.Add(delay)
+		log.Printf("[worker] container of task %s exited (%s, status %d), restarting it in %v\n", t.ID, reason, exitCode, delay)
+		defer w.notify()
+	} else {
+		t.State = task.Completed
+		if failed {
+			t.State = task.Failed
+		}
+		log.Printf("[worker] container of task %s exited (%s, status %d), task is %s after %d restarts\n", t.ID, reason, exitCode, t.State, t.RestartCount)
+	}
+	w.saveTask(t)
+}
+
+// nextRestart returns how long until the backoff of a task in CrashLoopBackOff elapses, or
+// ok=false if no task is waiting to be restarted. Busy tasks are left out, the end of their
+// operations wakes RunTasks up.
+func (w *Worker) nextRestart() (time.Duration, bool) {
+	var next time.Time
+	for _, t := range w.GetTasks() {
+		if t.State != task.CrashLoopBackOff || w.busy(t.ID.String()) {
+			continue
+		}
+		if next.IsZero() || t.RestartAt.Before(next) {
+			next = t.RestartAt
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return max(time.Until(next), 0), true
+}
+
+// restartTasks dispatches the restart of the tasks whose backoff has elapsed.
+func (w *Worker) restartTasks() {
+	now := time.Now()
+	for _, t := range w.GetTasks() {
+		id := t.ID.String()
+		if t.State != task.CrashLoopBackOff || now.Before(t.RestartAt) || w.busy(id) {
+			continue
+		}
+		w.dispatch(id, func() { w.restartTask(id) })
+	}
+}
+
+// restartTask replaces the exited container of a task in CrashLoopBackOff, unless the task was
+// stopped in the meantime.
+func (w *Worker) restartTask(taskID string) {
+	result, err := w.Db.Get(taskID)
+	if err != nil {
+		log.Printf("[worker] error restarting task %s: %v\n", taskID, err)
+		return
+	}
+	t := *result.(*task.Task)
+	if t.State != task.CrashLoopBackOff {
+		return
+	}
+
+	if t.ContainerID != "" {
+		config := task.NewConfig(&t)
+		d, err := task.NewDocker(config)
+		if err == nil {
+			// the container may already be gone, e.g. when a probe failure stopped it
+			d.Stop(t.ContainerID)
+		}
+	}
+
+	t.RestartCount++
+	t.RestartAt = time.Time{}
+	log.Printf("[worker] restarting task %s (restart %d)\n", t.ID, t.RestartCount)
+	taskRestarts.Inc(w.Name)
+	res := w.StartTask(t)
+	if res.Error != nil {
+		log.Printf("[worker] error restarting task %s: %v\n", t.ID, res.Error)
+	}
+}
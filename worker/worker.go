@@ -5,26 +5,83 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/ahmadateya/my-own-k8s/node"
 	"github.com/ahmadateya/my-own-k8s/stats"
 	"github.com/ahmadateya/my-own-k8s/store"
 	"github.com/ahmadateya/my-own-k8s/task"
 	"github.com/golang-collections/collections/queue"
 )
 
+// ResyncInterval is how often UpdateTasks inspects the containers of running tasks. Container
+// exits are normally picked up right away from Docker's events by WatchContainers.
+const ResyncInterval = time.Minute
+
+// StatsHistorySize is the number of stats samples kept for the worker and for each task,
+// an hour's worth at the rate CollectStats takes them.
+const StatsHistorySize = 240
+
 type Worker struct {
-	Name      string
-	Queue     queue.Queue
-	Db        store.Store
-	Stats     *stats.Stats
-	TaskCount int
+	Name         string
+	Queue        queue.Queue
+	Db           store.Store
+	Stats        *stats.Stats
+	StatsHistory *stats.History
+	MountPaths   []string // paths whose filesystem usage is reported besides the root filesystem
+	TaskCount    int
+	Eviction     EvictionThresholds
+	Pressure     []node.PressureCondition // resources the worker is currently evicting tasks to reclaim
+	// RestartBackoff is the delay before a task's container is first restarted; it doubles with
+	// every restart up to MaxRestartBackoff.
+	RestartBackoff    time.Duration
+	MaxRestartBackoff time.Duration
+	Parallelism       int // task operations run at the same time
+	// RemoveUnknownContainers makes Reconcile remove the containers labeled with the worker's
+	// name that don't belong to any of its tasks.
+	RemoveUnknownContainers bool
+
+	queueMu sync.Mutex
+	lanesMu sync.Mutex
+	lanes   map[string][]func() // [taskID]operations waiting to run, see dispatch
+	slots   chan struct{}       // one per running operation, created by the first dispatch
+
+	historyMu   sync.Mutex
+	taskHistory map[string]*stats.TaskHistory // [taskID]samples
+	nextProbe   map[string]time.Time          // [taskID/containerID/probe]when it is due
+	wake        chan struct{}                 // signals RunTasks that there is work to do
+
+	updatesMu       sync.Mutex
+	resourceVersion uint64               // of the last task stored, see saveTask
+	updates         map[string]task.Task // [taskID]latest version not pushed to the manager yet
+	pushWake        chan struct{}
+
+	eventsMu  sync.Mutex
+	oomKilled map[string]bool // [containerID]an oom event was received before its die event
+	unhealthy map[string]bool // [containerID]Docker reported the container as unhealthy
+
+	dockerMu sync.Mutex
+	docker   *task.Docker // shared by the calls that don't need a task's config, see dockerClient
 }
 
 func New(name string, taskDbType string) *Worker {
 	w := Worker{
-		Name:  name,
-		Queue: *queue.New(),
+		Name:                    name,
+		Queue:                   *queue.New(),
+		StatsHistory:            stats.NewHistory(StatsHistorySize),
+		RestartBackoff:          task.DefaultRestartBackoff,
+		MaxRestartBackoff:       task.DefaultMaxRestartBackoff,
+		Parallelism:             DefaultParallelism,
+		RemoveUnknownContainers: true,
+		lanes:                   make(map[string][]func()),
+		updates:                 make(map[string]task.Task),
+		pushWake:                make(chan struct{}, 1),
+		taskHistory:             make(map[string]*stats.TaskHistory),
+		nextProbe:               make(map[string]time.Time),
+		wake:                    make(chan struct{}, 1),
+		oomKilled:               make(map[string]bool),
+		unhealthy:               make(map[string]bool),
 	}
 
 	var s store.Store
@@ -40,6 +97,13 @@ func New(name string, taskDbType string) *Worker {
 		log.Printf("eunable to create new task store: %v", err)
 	}
 	w.Db = s
+	if s != nil {
+		// continue from the versions stored before a restart, the manager ignores versions that
+		// aren't newer than the last one it applied
+		for _, t := range w.GetTasks() {
+			w.resourceVersion = max(w.resourceVersion, t.ResourceVersion)
+		}
+	}
 	return &w
 }
 
@@ -56,39 +120,142 @@ func (w *Worker) GetTasks() []*task.Task {
 func (w *Worker) CollectStats() {
 	for {
 		log.Println("Collecting stats")
-		w.Stats = stats.GetStats()
+		w.Stats = stats.GetStats(w.MountPaths...)
 		w.TaskCount = w.Stats.TaskCount
+		w.StatsHistory.Add(*w.Stats)
+		w.collectTaskStats()
+		w.checkPressure()
 		time.Sleep(15 * time.Second)
 	}
 }
 
+// collectTaskStats records a sample for every running task and forgets the tasks that stopped.
+func (w *Worker) collectTaskStats() {
+	d, err := w.dockerClient()
+	if err != nil {
+		log.Printf("[worker] error collecting task stats: %v\n", err)
+		return
+	}
+
+	var samples []*stats.TaskStats
+	labels := make(map[string]map[string]string)
+	running := make(map[string]bool)
+	for _, t := range w.GetTasks() {
+		if t.State != task.Running {
+			continue
+		}
+		id := t.ID.String()
+		running[id] = true
+		labels[id] = t.Labels(w.Name)
+
+		s, err := taskStats(d, *t)
+		if err != nil {
+			log.Printf("[worker] error collecting stats for task %s: %v\n", id, err)
+			continue
+		}
+		w.historyMu.Lock()
+		h, ok := w.taskHistory[id]
+		if !ok {
+			h = stats.NewTaskHistory(StatsHistorySize)
+			w.taskHistory[id] = h
+		}
+		w.historyMu.Unlock()
+		h.Add(*s)
+		samples = append(samples, s)
+	}
+	w.setTaskMetrics(samples, labels)
+
+	w.historyMu.Lock()
+	defer w.historyMu.Unlock()
+	for id := range w.taskHistory {
+		if !running[id] {
+			delete(w.taskHistory, id)
+		}
+	}
+}
+
+// dockerClient returns the Docker client the worker reuses for calls that don't depend on a task's
+// config, such as collecting stats, creating it on first use.
+func (w *Worker) dockerClient() (*task.Docker, error) {
+	w.dockerMu.Lock()
+	defer w.dockerMu.Unlock()
+	if w.docker == nil {
+		d, err := task.NewDocker(&task.Config{})
+		if err != nil {
+			return nil, err
+		}
+		w.docker = d
+	}
+	return w.docker, nil
+}
+
+// GetTaskStats returns the latest stats sample CollectStats took of a running task's container.
+func (w *Worker) GetTaskStats(taskID string) (*stats.TaskStats, error) {
+	w.historyMu.Lock()
+	h, ok := w.taskHistory[taskID]
+	w.historyMu.Unlock()
+	if !ok || h.Latest() == nil {
+		return nil, fmt.Errorf("no stats recorded for task %s", taskID)
+	}
+	return h.Latest(), nil
+}
+
+// GetAllTaskStats returns the latest stats sample CollectStats took of each running task.
+func (w *Worker) GetAllTaskStats() []*stats.TaskStats {
+	w.historyMu.Lock()
+	defer w.historyMu.Unlock()
+	samples := []*stats.TaskStats{}
+	for _, h := range w.taskHistory {
+		if s := h.Latest(); s != nil {
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// GetTaskStatsSeries returns the stats samples of a task taken at or after since, at most one per step.
+func (w *Worker) GetTaskStatsSeries(taskID string, since time.Time, step time.Duration) ([]stats.TaskPoint, error) {
+	w.historyMu.Lock()
+	h, ok := w.taskHistory[taskID]
+	w.historyMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no stats recorded for task %s", taskID)
+	}
+	return h.Series(since, step), nil
+}
+
+// RunTasks processes the queue as soon as tasks are added to it, and restarts the tasks in
+// CrashLoopBackOff when their backoff elapses. Up to Parallelism operations run concurrently.
 func (w *Worker) RunTasks() {
 	for {
-		if w.Queue.Len() != 0 {
-			result := w.runTask()
-			if result.Error != nil {
-				log.Printf("Error running task: %v\n", result.Error)
+		w.runQueued()
+		w.restartTasks()
+
+		if delay, ok := w.nextRestart(); ok {
+			timer := time.NewTimer(delay)
+			select {
+			case <-w.wake:
+			case <-timer.C:
 			}
+			timer.Stop()
 		} else {
-			log.Printf("No tasks to process currently.\n")
+			<-w.wake
 		}
-		log.Println("Sleeping for 10 seconds.")
-		time.Sleep(10 * time.Second)
 	}
-
 }
 
-func (w *Worker) runTask() task.DockerResult {
-	t := w.Queue.Dequeue()
-	if t == nil {
-		log.Println("[worker] No tasks in the queue")
-		return task.DockerResult{Error: nil}
+// notify wakes RunTasks up. It never blocks: a pending wake-up covers any number of changes.
+func (w *Worker) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
 	}
+}
 
-	taskQueued := t.(task.Task)
+func (w *Worker) runTask(taskQueued task.Task) task.DockerResult {
 	fmt.Printf("[worker] Found task in queue: %v:\n", taskQueued)
 
-	err := w.Db.Put(taskQueued.ID.String(), &taskQueued)
+	err := w.saveTask(&taskQueued)
 	if err != nil {
 		msg := fmt.Errorf("error storing task %s: %v", taskQueued.ID.String(), err)
 		log.Println(msg)
@@ -133,98 +300,161 @@ func (w *Worker) runTask() task.DockerResult {
 
 func (w *Worker) StartTask(t task.Task) task.DockerResult {
 	config := task.NewConfig(&t)
+	config.Labels = t.Labels(w.Name)
 	d, _ := task.NewDocker(config)
 	result := d.Run()
 	if result.Error != nil {
 		log.Printf("Err running task %v: %v\n", t.ID, result.Error)
 		t.State = task.Failed
-		w.Db.Put(t.ID.String(), &t)
+		w.saveTask(&t)
 		return result
 	}
 
 	t.ContainerID = result.ContainerId
 	t.State = task.Running
-	w.Db.Put(t.ID.String(), &t)
+	t.StartTime = time.Now().UTC()
+	t.Ready = false
+	t.Probes = task.ProbeStatuses{}
+	// the published ports are needed by the probes right away
+	resp := d.Inspect(t.ContainerID)
+	if resp.Error == nil && resp.Container.NetworkSettings != nil {
+		t.HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
+	}
+	w.saveTask(&t)
 
 	return result
 }
 
 func (w *Worker) StopTask(t task.Task) task.DockerResult {
+	return w.stopTask(t, task.Completed)
+}
+
+// stopTask stops the task's container and records the task in the given final state.
+func (w *Worker) stopTask(t task.Task, state task.State) task.DockerResult {
 	config := task.NewConfig(&t)
 	d, err := task.NewDocker(config)
 	if err != nil {
-		slog.Error("Error creating Docker client: %v\n", err)
+		slog.Error("Error creating Docker client", "error", err)
 		return task.DockerResult{Error: err}
 	}
 
+	// record the final state first, so that the container's die event isn't taken for an exit
+	t.FinishTime = time.Now().UTC()
+	t.State = state
+	t.Ready = false
+	w.saveTask(&t)
+
 	result := d.Stop(t.ContainerID)
 	if result.Error != nil {
-		slog.Error("Error stopping container %v: %v\n", t.ContainerID, result.Error)
+		slog.Error("Error stopping container", "task", t.ID, "container", t.ContainerID, "error", result.Error)
+	}
+	// remove whatever else was created for the task, e.g. the container of an interrupted restart
+	err = d.RemoveLabeled(w.ownedBy(t.ID.String()))
+	if err != nil {
+		slog.Error("Error cleaning up Docker objects of task", "task", t.ID, "error", err)
+	}
+	if result.Error == nil {
+		slog.Info("Stopped and removed container", "task", t.ID, "container", t.ContainerID, "state", t.State)
 	}
-
-	t.FinishTime = time.Now().UTC()
-	t.State = task.Completed
-	w.Db.Put(t.ID.String(), &t)
-	slog.Error("Stopped and removed container %v for task %v\n", t.ContainerID, t.ID)
 	return result
 }
 
 func (w *Worker) AddTask(t task.Task) {
-	w.Queue.Enqueue(t)
+	// an evicted task sent back to this worker is no longer evicted, record that right away so
+	// the manager doesn't reschedule it again before the queue is processed
+	result, err := w.Db.Get(t.ID.String())
+	if err == nil {
+		if persisted, ok := result.(*task.Task); ok && persisted.State == task.Evicted && t.State == task.Scheduled {
+			w.saveTask(&t)
+		}
+		// likewise a task waiting to be restarted must not be restarted once it is being stopped
+		if persisted, ok := result.(*task.Task); ok && persisted.State == task.CrashLoopBackOff && t.State == task.Completed {
+			w.saveTask(&t)
+		}
+	}
+	w.enqueue(t)
+	w.notify()
 }
 
 func (w *Worker) InspectTask(t task.Task) task.DockerInspectResponse {
 	config := task.NewConfig(&t)
 	d, err := task.NewDocker(config)
 	if err != nil {
-		slog.Error("Error creating Docker client: %v\n", err)
+		slog.Error("Error creating Docker client", "error", err)
 		return task.DockerInspectResponse{Error: err}
 	}
 	return d.Inspect(t.ContainerID)
 }
 
+func taskStats(d *task.Docker, t task.Task) (*stats.TaskStats, error) {
+	resp := d.Stats(t.ContainerID)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return stats.NewTaskStats(t.ID, t.Name, resp.Stats), nil
+}
+
 func (w *Worker) UpdateTasks() {
 	for {
 		log.Println("Checking status of tasks")
 		w.updateTasks()
 		log.Println("Task updates completed")
-		log.Println("Sleeping for 15 seconds")
-		time.Sleep(15 * time.Second)
+		log.Printf("Sleeping for %v\n", ResyncInterval)
+		time.Sleep(ResyncInterval)
 	}
 }
 
 func (w *Worker) updateTasks() {
-	// for each task in the worker's datastore:
+	// for each running task in the worker's datastore, on the task's lane:
 	// 1. call InspectTask method
-	// 2. verify task is in running state
-	// 3. if task is not in running state, or not running at all, mark task as `failed`
+	// 2. verify task is still in running state
+	// 3. if the container exited or is gone, apply the task's restart policy
 	tasks, err := w.Db.List()
 	if err != nil {
 		log.Printf("error getting list of tasks: %v\n", err)
 		return
 	}
 	for _, t := range tasks.([]*task.Task) {
-		if t.State == task.Running {
-			resp := w.InspectTask(*t)
-			if resp.Error != nil {
-				fmt.Printf("ERROR: %v\n", resp.Error)
-			}
+		if t.State != task.Running {
+			continue
+		}
+		id, containerID := t.ID.String(), t.ContainerID
+		w.dispatch(id, func() { w.updateTask(id, containerID) })
+	}
+}
 
-			if resp.Container == nil {
-				log.Printf("No container for running task %s\n", t.ID)
-				t.State = task.Failed
-				w.Db.Put(t.ID.String(), t)
-			}
+func (w *Worker) updateTask(taskID string, containerID string) {
+	t := w.runningTask(taskID, containerID)
+	if t == nil {
+		return
+	}
 
-			if resp.Container.State.Status == "exited" {
-				log.Printf("Container for task %s in non-running state %s\n", t.ID, resp.Container.State.Status)
-				t.State = task.Failed
-				w.Db.Put(t.ID.String(), t)
-			}
+	resp := w.InspectTask(*t)
+	if resp.Error != nil {
+		fmt.Printf("ERROR: %v\n", resp.Error)
+	}
 
-			// task is running, update exposed ports
-			t.HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
-			w.Db.Put(t.ID.String(), t)
+	if resp.Container == nil {
+		log.Printf("No container for running task %s\n", t.ID)
+		w.containerExited(t, -1, ExitContainerMissing)
+		return
+	}
+
+	state := resp.Container.State
+	if state.Status == "exited" || state.Status == "dead" {
+		log.Printf("Container for task %s in non-running state %s\n", t.ID, state.Status)
+		reason := ExitCompleted
+		switch {
+		case state.OOMKilled:
+			reason = ExitOOMKilled
+		case state.ExitCode != 0:
+			reason = ExitError
 		}
+		w.containerExited(t, state.ExitCode, reason)
+		return
 	}
+
+	// task is running, update exposed ports
+	t.HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
+	w.saveTask(t)
 }